@@ -0,0 +1,343 @@
+package v1
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+)
+
+// attachmentRequest sends a request with a raw body, authorised by a proof header.
+func attachmentRequest(client *http.Client, method, url, contentType, proof string, body []byte) (res *http.Response, err error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Proof", proof)
+
+	res, err = client.Do(req)
+	if err != nil {
+		return
+	}
+
+	res.Body.Close()
+	return
+}
+
+// attachments tests attaching encrypted files to a pad, and that they are removed with the pad.
+func attachments(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "attached-pad",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	url := baseURL + "pad/" + original.ID + "/attachments"
+	data := []byte("ENCRYPTED-ATTACHMENT")
+
+	// Attachments are raw bytes, not JSON.
+	res, err := attachmentRequest(client, http.MethodPost, url, "application/json", original.NewProof, data)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("attachments: json attachment was accepted (%v)", res.Status)
+	}
+
+	res, err = attachmentRequest(client, http.MethodPost, url, "application/octet-stream", "INCORRECT-PROOF-ABCDEFGHIJKLMNOP", data)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("attachments: attachment was accepted with an incorrect proof (%v)", res.Status)
+	}
+
+	res, err = attachmentRequest(client, http.MethodPost, url, "application/octet-stream", original.NewProof, bytes.Repeat([]byte("A"), 33))
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("attachments: attachment larger than the maximum was accepted (%v)", res.Status)
+	}
+
+	// The test config allows 2 attachments per pad.
+	for i := 0; i < 2; i++ {
+		res, err = attachmentRequest(client, http.MethodPost, url, "application/octet-stream", original.NewProof, data)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != http.StatusCreated {
+			t.Errorf("attachments: could not attach file (%v)", res.Status)
+			return
+		}
+	}
+
+	res, err = attachmentRequest(client, http.MethodPost, url, "application/octet-stream", original.NewProof, data)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusConflict {
+		t.Errorf("attachments: too many attachments were accepted (%v)", res.Status)
+	}
+
+	var list []model.Attachment
+	res, err, errorResponse := getRequest(t, client, &list, url)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK || len(list) != 2 || list[0].Size != int64(len(data)) {
+		t.Errorf("attachments: unexpected attachments (%v, %v, %+v)", res.Status, errorResponse.Error, list)
+		return
+	}
+
+	res, output, err := rawRequest(client, http.MethodGet, url+"/"+list[0].ID, nil)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK || !bytes.Equal(output, data) {
+		t.Errorf("attachments: downloaded attachment differs (%v)", res.Status)
+	}
+
+	res, err = attachmentRequest(client, http.MethodDelete, url+"/"+list[0].ID, "", original.NewProof, nil)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("attachments: could not remove attachment (%v)", res.Status)
+	}
+
+	// Deleting the pad removes the rest of its attachments.
+	res, err, errorResponse = request(t, client, model.Pad{ID: original.ID, Proof: original.NewProof}, nil, http.MethodDelete, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("attachments: could not delete pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	list, err = pad.Store.Attachments(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(list) != 0 {
+		t.Errorf("attachments: attachments were kept after deleting the pad %+v", list)
+	}
+}
+
+// attachmentsSigned tests a signed attachment can only be added once, as its signature covers the attachment's ID.
+func attachmentsSigned(t *testing.T, client *http.Client) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	original := model.Pad{
+		ID:           "attached-signed",
+		Content:      "ENCRYPTED-STUFF-HERE",
+		NewPublicKey: base64.StdEncoding.EncodeToString(public),
+	}
+
+	err = pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	url := baseURL + "pad/" + original.ID + "/attachments"
+	data := []byte("ENCRYPTED-ATTACHMENT")
+	id := base64.RawURLEncoding.EncodeToString(bytes.Repeat([]byte{1}, 16))
+
+	hash := sha256.Sum256(data)
+	message := "cryptopad-attachment\n" + original.ID + "\n" + id + "\n" + hex.EncodeToString(hash[:]) + "\n1"
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(private, []byte(message)))
+
+	// Sending the same signed request again must not attach the file again.
+	for i, status := range []int{http.StatusCreated, http.StatusConflict} {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set("X-Attachment", id)
+		req.Header.Set("X-Signature", signature)
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		res.Body.Close()
+
+		if res.StatusCode != status {
+			t.Errorf("attachments signed: request %v was %v rather than %v", i, res.Status, status)
+			return
+		}
+	}
+
+	list, err := pad.Store.Attachments(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(list) != 1 || list[0].ID != id {
+		t.Errorf("attachments signed: unexpected attachments %+v", list)
+		return
+	}
+
+	message = "cryptopad-remove-attachment\n" + original.ID + "\n" + id + "\n1"
+	req, err := http.NewRequest(http.MethodDelete, url+"/"+id, nil)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	req.Header.Set("X-Signature", base64.StdEncoding.EncodeToString(ed25519.Sign(private, []byte(message))))
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("attachments signed: delete was %v", res.Status)
+		return
+	}
+
+	// Replaying the signed request once the attachment is removed must not add it back.
+	req, err = http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Attachment", id)
+	req.Header.Set("X-Signature", signature)
+
+	res, err = client.Do(req)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusConflict {
+		t.Errorf("attachments signed: replay after delete was %v", res.Status)
+		return
+	}
+
+	list, err = pad.Store.Attachments(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(list) != 0 {
+		t.Errorf("attachments signed: removed attachment was added back %+v", list)
+	}
+}
+
+// attachmentsConcurrent tests attachments sent at once can't go over the maximum per pad.
+func attachmentsConcurrent(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "attached-race",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	url := baseURL + "pad/" + original.ID + "/attachments"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			attachmentRequest(client, http.MethodPost, url, "application/octet-stream", original.NewProof, []byte("ENCRYPTED-ATTACHMENT"))
+		}()
+	}
+
+	wg.Wait()
+
+	// The test config allows 2 attachments per pad.
+	list, err := pad.Store.Attachments(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(list) != 2 {
+		t.Errorf("attachments concurrent: pad has %v attachments rather than 2", len(list))
+	}
+
+	err = pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
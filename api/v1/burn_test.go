@@ -0,0 +1,193 @@
+package v1
+
+import (
+	"database/sql"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+	"github.com/VolticFroogo/cryptopad-server/db"
+	"github.com/gchaincl/dotsql"
+)
+
+// getBurn tests that a burn after reading pad can be read once, and is then gone.
+func getBurn(t *testing.T, client *http.Client) {
+	body := model.Pad{
+		ID:               "burn-pad",
+		Content:          "ENCRYPTED-STUFF-HERE",
+		NewProof:         "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		BurnAfterReading: true,
+	}
+
+	err := pad.Remove(body.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	res, err, errorResponse := request(t, client, body, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("get burn: could not put new pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	output := &model.Pad{}
+
+	res, err, errorResponse = getRequest(t, client, output, baseURL+"pad/"+body.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK || output.Content != body.Content {
+		t.Errorf("get burn: could not read pad before it was burnt (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	res, err, errorResponse = getRequest(t, client, nil, baseURL+"pad/"+body.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusGone {
+		t.Errorf("get burn: expected status gone after reading (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("get burn: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// getBurnConcurrent tests that only one of many concurrent readers gets a burn after reading pad.
+func getBurnConcurrent(t *testing.T, client *http.Client) {
+	const readers = 8
+
+	body := model.Pad{
+		ID:               "burn-race",
+		Content:          "ENCRYPTED-STUFF-HERE",
+		NewProof:         "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		BurnAfterReading: true,
+	}
+
+	err := pad.Remove(body.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(body)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	var wg sync.WaitGroup
+	statuses := make(chan int, readers)
+
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			res, err, _ := getRequest(t, client, nil, baseURL+"pad/"+body.ID)
+			if err != nil {
+				t.Error(err.Error())
+				return
+			}
+
+			statuses <- res.StatusCode
+		}()
+	}
+
+	wg.Wait()
+	close(statuses)
+
+	read := 0
+	for status := range statuses {
+		switch status {
+		case http.StatusOK:
+			read++
+		case http.StatusGone:
+		default:
+			t.Errorf("get burn concurrent: unexpected status %v", status)
+			return
+		}
+	}
+
+	if read != 1 {
+		t.Errorf("get burn concurrent: expected exactly one reader, got %v", read)
+		return
+	}
+
+	t.Log("get burn concurrent: success")
+}
+
+// burnLockout tests burning a pad removes its lockout, in the configured store and in an SQLite store.
+func burnLockout(t *testing.T, client *http.Client) {
+	conn, err := sql.Open(db.DriverSQLite, ":memory:")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	defer conn.Close()
+
+	// Each connection to an in-memory database has its own, so share one.
+	conn.SetMaxOpenConns(1)
+
+	queries, err := dotsql.LoadFromFile("../../sql/queries_sqlite.sql")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	sqlite := pad.NewSQLStore(conn, queries, 3)
+
+	err = sqlite.CreateTables()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	body := model.Pad{
+		ID:               "burn-lockout",
+		Content:          "ENCRYPTED-STUFF-HERE",
+		NewProof:         "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		BurnAfterReading: true,
+	}
+
+	for _, store := range []pad.PadStore{pad.Store, sqlite} {
+		err = store.Remove(body.ID)
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		err = store.Insert(body)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		_, err = store.AddFailure(body.ID)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		_, err = store.Burn(body.ID)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		lockout, err := store.Lockout(body.ID)
+		if err != sql.ErrNoRows {
+			t.Errorf("burn lockout: %T kept the lockout of a burnt pad (%+v, %v)", store, lockout, err)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package v1
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+)
+
+// getReadToken tests that a pad with a read token can only be read with the token.
+func getReadToken(t *testing.T, client *http.Client) {
+	body := model.Pad{
+		ID:           "read-token",
+		Content:      "ENCRYPTED-STUFF-HERE",
+		NewProof:     "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		NewReadToken: "READ-KEY-ABCDEFGHIJKLMNOPQRSTUVW",
+	}
+
+	err := pad.Remove(body.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(body)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	res, err, errorResponse := getRequest(t, client, nil, baseURL+"pad/"+body.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("get read token: expected status forbidden without token (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	header := http.Header{}
+	header.Set("X-Read-Token", body.NewReadToken)
+	output := &model.Pad{}
+
+	res, err, errorResponse = requestWithHeader(t, client, nil, output, http.MethodGet, baseURL+"pad/"+body.ID, header)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK || output.Content != body.Content || output.ReadToken != "" {
+		t.Errorf("get read token: could not get pad with token (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	res, err, errorResponse = getRequest(t, client, nil, baseURL+"pad/"+body.ID+"?token="+body.NewReadToken)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("get read token: could not get pad with token query (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("get read token: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// putEditor tests that an owner can add an editor, who can only update the content.
+func putEditor(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "editor-pad",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	add := model.Pad{
+		Proof:    "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		NewProof: "EDITOR-KEY-ABCDEFGHIJKLMNOPQRSTU",
+	}
+
+	res, err, errorResponse := request(t, client, add, nil, http.MethodPut, baseURL+"pad/editor-pad/editors/alice")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("put editor: could not add editor (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	updated := model.Pad{
+		ID:      "editor-pad",
+		Content: "OTHER-ENCRYPTED-STUFF-HERE",
+		Editor:  "alice",
+		Proof:   "EDITOR-KEY-ABCDEFGHIJKLMNOPQRSTU",
+	}
+
+	res, err, errorResponse = request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("put editor: editor could not update content (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	// The owner's proof should be untouched by the editor's update.
+	output, err := pad.FromID(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	if !pad.VerifyProof(output.Proof, original.NewProof) {
+		t.Errorf("put editor: editor's update changed the owner's proof (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	updated.NewProof = "OTHER-KEY-ABCDEFGHIJKLMNOPQRSTUV"
+
+	res, err, errorResponse = request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("put editor: expected status forbidden for editor rotating the proof (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	remove := model.Pad{
+		ID:     "editor-pad",
+		Editor: "alice",
+		Proof:  "EDITOR-KEY-ABCDEFGHIJKLMNOPQRSTU",
+	}
+
+	res, err, errorResponse = request(t, client, remove, nil, http.MethodDelete, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("put editor: expected status forbidden for editor deleting the pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("put editor: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// deleteEditor tests that a removed editor can no longer update a pad.
+func deleteEditor(t *testing.T, client *http.Client) {
+	body := model.Pad{
+		Proof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	res, err, errorResponse := request(t, client, body, nil, http.MethodDelete, baseURL+"pad/editor-pad/editors/alice")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("delete editor: could not remove editor (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	updated := model.Pad{
+		ID:      "editor-pad",
+		Content: "MALICIOUS-STUFF-HERE",
+		Editor:  "alice",
+		Proof:   "EDITOR-KEY-ABCDEFGHIJKLMNOPQRSTU",
+	}
+
+	res, err, errorResponse = request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("delete editor: expected status forbidden for removed editor (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("delete editor: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// editorSigned tests that signed changes to editors need a challenge, so a removed editor can't be given back their
+// proof by replaying the signed request which added them.
+func editorSigned(t *testing.T, client *http.Client) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	original := model.Pad{
+		ID:           "editor-signed",
+		Content:      "ENCRYPTED-STUFF-HERE",
+		NewPublicKey: base64.StdEncoding.EncodeToString(public),
+	}
+
+	err = pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	url := baseURL + "pad/" + original.ID + "/editors/alice"
+	proof := "EDITOR-KEY-ABCDEFGHIJKLMNOPQRSTU"
+	hash := sha256.Sum256([]byte(proof))
+
+	challenge, _ := answer(t, client, original.ID, private)
+	add := model.Pad{
+		NewProof:  proof,
+		Challenge: challenge,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(private, []byte(
+			"cryptopad-editor\n"+original.ID+"\nalice\n"+hex.EncodeToString(hash[:])+"\n1\n"+challenge))),
+	}
+
+	challenge, _ = answer(t, client, original.ID, private)
+	remove := model.Pad{
+		Challenge: challenge,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(private, []byte(
+			"cryptopad-remove-editor\n"+original.ID+"\nalice\n1\n"+challenge))),
+	}
+
+	noChallenge := add
+	noChallenge.Challenge = ""
+
+	for i, step := range []struct {
+		method string
+		body   model.Pad
+		status int
+	}{
+		{http.MethodPut, noChallenge, http.StatusBadRequest},
+		{http.MethodPut, add, http.StatusOK},
+		{http.MethodDelete, remove, http.StatusOK},
+		{http.MethodPut, add, http.StatusForbidden},
+	} {
+		res, err, errorResponse := request(t, client, step.body, nil, step.method, url)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != step.status {
+			t.Errorf("editor signed: request %v was %v rather than %v (%v)", i, res.Status, step.status, errorResponse.Error)
+			return
+		}
+	}
+
+	_, err = pad.Store.Editor(original.ID, "alice")
+	if err != sql.ErrNoRows {
+		t.Errorf("editor signed: removed editor was added back (%v)", err)
+		return
+	}
+
+	t.Logf("editor signed: success")
+}
@@ -0,0 +1,171 @@
+package v1
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+)
+
+// answer gets a challenge for a pad and signs it with a private key.
+func answer(t *testing.T, client *http.Client, id string, key ed25519.PrivateKey) (challenge, response string) {
+	output := &model.Challenge{}
+
+	res, err, errorResponse := request(t, client, nil, output, http.MethodPost, baseURL+"pad/"+id+"/challenge")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("answer: could not get challenge (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	challenge = output.Challenge
+	response = base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte("cryptopad-challenge\n"+id+"\n"+challenge)))
+	return
+}
+
+// putChallenge tests updating a pad with a signed challenge instead of the proof, and that challenges are single use.
+func putChallenge(t *testing.T, client *http.Client) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	original := model.Pad{
+		ID:           "challenge-pad",
+		Content:      "ENCRYPTED-STUFF-HERE",
+		NewProof:     "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		NewPublicKey: base64.StdEncoding.EncodeToString(public),
+	}
+
+	err = pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	res, err, errorResponse := request(t, client, original, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("put challenge: could not put new pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	challenge, response := answer(t, client, original.ID, private)
+
+	updated := model.Pad{
+		ID:        "challenge-pad",
+		Content:   "OTHER-ENCRYPTED-STUFF-HERE",
+		Challenge: challenge,
+		Response:  response,
+	}
+
+	res, err, errorResponse = request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("put challenge: could not update pad with challenge (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	// The proof should still work after updating with a challenge.
+	output, err := pad.FromID(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	if !pad.VerifyProof(output.Proof, original.NewProof) {
+		t.Errorf("put challenge: proof was changed by updating with a challenge (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	res, err, errorResponse = request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("put challenge: expected status forbidden reusing a challenge (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("put challenge: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// putIncorrectResponse tests updating a pad with a challenge signed by the wrong key.
+func putIncorrectResponse(t *testing.T, client *http.Client) {
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	challenge, response := answer(t, client, "challenge-pad", other)
+
+	updated := model.Pad{
+		ID:        "challenge-pad",
+		Content:   "OTHER-ENCRYPTED-STUFF-HERE",
+		Challenge: challenge,
+		Response:  response,
+	}
+
+	res, err, errorResponse := request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("put incorrect response: expected status forbidden (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("put incorrect response: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// challengeNoPublicKey tests getting a challenge for a pad without a public key.
+func challengeNoPublicKey(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "no-key-pad",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	res, err, errorResponse := request(t, client, nil, nil, http.MethodPost, baseURL+"pad/"+original.ID+"/challenge")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("challenge no public key: expected status bad request (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("challenge no public key: success (%v, %v)", res.Status, errorResponse.Error)
+}
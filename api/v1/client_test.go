@@ -0,0 +1,101 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+	"github.com/VolticFroogo/cryptopad-server/client"
+)
+
+// clientPad tests creating, loading, updating, rotating the key of and deleting a pad with the Go client.
+func clientPad(t *testing.T, httpClient *http.Client) {
+	id := "client-pad"
+
+	err := pad.Remove(id)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	c := client.New(location + port)
+	c.HTTP = httpClient
+	c.Iterations = model.MinPBKDF2Iterations
+
+	created, err := c.Create(id, "PASSWORD", "SECRET TEXT")
+	if err != nil {
+		t.Errorf("client: could not create pad (%v)", err)
+		return
+	}
+
+	_, err = c.Create(id, "PASSWORD", "OTHER TEXT")
+	if err != client.ErrUnavailable {
+		t.Errorf("client: created a pad which already exists (%v)", err)
+	}
+
+	loaded, err := c.Load(id, "PASSWORD")
+	if err != nil {
+		t.Errorf("client: could not load pad (%v)", err)
+		return
+	}
+
+	if loaded.Text != created.Text || loaded.Proof != created.Proof || loaded.Revision != 1 {
+		t.Errorf("client: loaded pad doesn't match (%+v, %+v)", loaded, created)
+	}
+
+	_, err = c.Load(id, "INCORRECT-PASSWORD")
+	if err != client.ErrDecrypt {
+		t.Errorf("client: loaded pad with an incorrect password (%v)", err)
+	}
+
+	loaded.Text = "NEW SECRET TEXT"
+	err = c.Update(loaded, "PASSWORD")
+	if err != nil {
+		t.Errorf("client: could not update pad (%v)", err)
+		return
+	}
+
+	// The created pad is now out of date, so saving it would overwrite the update.
+	err = c.Update(created, "PASSWORD")
+	if !errors.Is(err, client.ErrPreconditionFailed) {
+		t.Errorf("client: stale update wasn't rejected (%v)", err)
+	}
+
+	err = c.RotateKey(loaded, "PASSWORD", "NEW-PASSWORD")
+	if err != nil {
+		t.Errorf("client: could not rotate key (%v)", err)
+		return
+	}
+
+	_, err = c.Load(id, "PASSWORD")
+	if err != client.ErrDecrypt {
+		t.Errorf("client: loaded pad with the old password (%v)", err)
+	}
+
+	rotated, err := c.Load(id, "NEW-PASSWORD")
+	if err != nil {
+		t.Errorf("client: could not load rotated pad (%v)", err)
+		return
+	}
+
+	if rotated.Text != loaded.Text || rotated.Proof == created.Proof || rotated.Revision != 3 {
+		t.Errorf("client: rotated pad doesn't match (%+v)", rotated)
+	}
+
+	// The old proof no longer works.
+	err = c.Delete(created)
+	if !errors.Is(err, client.ErrForbidden) {
+		t.Errorf("client: deleted pad with the old proof (%v)", err)
+	}
+
+	err = c.Delete(rotated)
+	if err != nil {
+		t.Errorf("client: could not delete pad (%v)", err)
+	}
+
+	_, err = c.Load(id, "NEW-PASSWORD")
+	if !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("client: loaded deleted pad (%v)", err)
+	}
+}
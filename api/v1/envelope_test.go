@@ -0,0 +1,98 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+)
+
+// envelopes tests putting content in envelopes, rejecting malformed envelopes, and getting the envelope version.
+func envelopes(t *testing.T, client *http.Client) {
+	env := model.Envelope{
+		Version:    model.EnvelopeCBC,
+		KDF:        model.KDFPBKDF2,
+		Iterations: 100000,
+		Salt:       bytes.Repeat([]byte{1}, 16),
+		IV:         bytes.Repeat([]byte{2}, 16),
+		Ciphertext: bytes.Repeat([]byte{3}, 32),
+		MAC:        bytes.Repeat([]byte{4}, 32),
+	}
+
+	original := model.Pad{
+		ID:       "enveloped-pad",
+		Content:  model.Ciphertext(base64.StdEncoding.EncodeToString(env.Bytes())),
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	res, err, errorResponse := request(t, client, original, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("envelopes: could not put enveloped pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	output := &model.Pad{}
+
+	res, err, errorResponse = getRequest(t, client, output, baseURL+"pad/"+original.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("envelopes: could not get enveloped pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	if output.Envelope != model.EnvelopeCBC || output.Content != original.Content {
+		t.Errorf("envelopes: got envelope version %v, expected %v", output.Envelope, model.EnvelopeCBC)
+	}
+
+	// Each of these starts like an envelope, but isn't one.
+	unknownVersion := env
+	unknownVersion.Version = 9
+
+	weakKDF := env
+	weakKDF.Iterations = 1
+
+	partialBlock := env
+	partialBlock.Ciphertext = partialBlock.Ciphertext[:31]
+
+	truncated := env.Bytes()[:40]
+
+	for name, content := range map[string][]byte{
+		"unknown version": unknownVersion.Bytes(),
+		"weak kdf":        weakKDF.Bytes(),
+		"partial block":   partialBlock.Bytes(),
+		"truncated":       truncated,
+	} {
+		malformed := model.Pad{
+			ID:      original.ID,
+			Content: model.Ciphertext(base64.StdEncoding.EncodeToString(content)),
+			Proof:   original.NewProof,
+		}
+
+		res, err, _ = request(t, client, malformed, nil, http.MethodPut, baseURL+"pad")
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("envelopes: %v envelope was accepted (%v)", name, res.Status)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package v1
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+)
+
+// readEvent reads the next server-sent event from a stream, skipping comments.
+func readEvent(reader *bufio.Reader) (id, name string, event model.Event, err error) {
+	for {
+		var line string
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if name != "" {
+				return
+			}
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			err = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event)
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// events tests that event streams resume from the Last-Event-ID, then stream updates and deletion.
+func events(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "events-pad",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	res, err, errorResponse := request(t, client, original, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("events: could not put new pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	updated := model.Pad{
+		ID:    original.ID,
+		Proof: original.NewProof,
+	}
+
+	// Make revisions 2 and 3, which a client at revision 1 has missed.
+	for _, content := range []model.Ciphertext{"SECOND-ENCRYPTED-STUFF", "THIRD-ENCRYPTED-STUFF"} {
+		updated.Content = content
+
+		res, err, errorResponse = request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("events: could not update pad (%v, %v)", res.Status, errorResponse.Error)
+			return
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, baseURL+"pad/"+original.ID+"/events", nil)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	req.Header.Set("Last-Event-ID", "1")
+
+	stream, err := client.Do(req)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	defer stream.Body.Close()
+
+	if stream.StatusCode != http.StatusOK {
+		t.Errorf("events: could not stream events (%v)", stream.Status)
+		return
+	}
+
+	reader := bufio.NewReader(stream.Body)
+
+	for _, expected := range []model.Event{
+		{Type: pad.EventUpdated, Revision: 2, Content: "SECOND-ENCRYPTED-STUFF"},
+		{Type: pad.EventUpdated, Revision: 3, Content: "THIRD-ENCRYPTED-STUFF"},
+	} {
+		_, name, event, err := readEvent(reader)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if name != expected.Type || event.Revision != expected.Revision || event.Content != expected.Content {
+			t.Errorf("events: unexpected replayed event %v %+v", name, event)
+		}
+	}
+
+	updated.Content = "FOURTH-ENCRYPTED-STUFF"
+
+	res, err, errorResponse = request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("events: could not update pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	id, name, event, err := readEvent(reader)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if name != pad.EventUpdated || id != "4" || event.Content != updated.Content {
+		t.Errorf("events: unexpected update event %v %v %+v", id, name, event)
+	}
+
+	res, err, errorResponse = request(t, client, updated, nil, http.MethodDelete, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("events: could not delete pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	_, name, _, err = readEvent(reader)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if name != pad.EventDeleted {
+		t.Errorf("events: unexpected delete event %v", name)
+	}
+}
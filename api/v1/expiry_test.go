@@ -0,0 +1,195 @@
+package v1
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+	"github.com/gorilla/websocket"
+)
+
+// putTTL tests creating a pad with a time to live.
+func putTTL(t *testing.T, client *http.Client) {
+	body := model.Pad{
+		ID:       "ttl-pad",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		TTL:      60,
+	}
+
+	err := pad.Remove(body.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	now := time.Now().Unix()
+
+	res, err, errorResponse := request(t, client, body, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("put ttl: could not put new pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	output, err := pad.FromID(body.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	if output.ExpiresAt < now+body.TTL || output.ExpiresAt > now+body.TTL+1 {
+		t.Errorf("put ttl: expected expiry 60 seconds from now, got %v (%v, %v)", output.ExpiresAt-now, res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("put ttl: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// putExpired tests creating a pad with an expiry in the past.
+func putExpired(t *testing.T, client *http.Client) {
+	body := model.Pad{
+		ID:        "expired-pad",
+		Content:   "ENCRYPTED-STUFF-HERE",
+		NewProof:  "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		ExpiresAt: time.Now().Unix() - 60,
+	}
+
+	res, err, errorResponse := request(t, client, body, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("put expired: expected status bad request (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("put expired: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// getExpired tests getting a pad which has expired but hasn't been swept.
+func getExpired(t *testing.T, client *http.Client) {
+	body := model.Pad{
+		ID:        "expired-pad",
+		Content:   "ENCRYPTED-STUFF-HERE",
+		NewProof:  "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		ExpiresAt: time.Now().Unix() - 60,
+	}
+
+	err := pad.Remove(body.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(body)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	res, err, errorResponse := getRequest(t, client, nil, baseURL+"pad/"+body.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("get expired: expected status not found (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("get expired: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// removeExpired tests that sweeping removes expired pads, and only expired pads, closing their subscribers.
+func removeExpired(t *testing.T, client *http.Client) {
+	expired := model.Pad{
+		ID:        "swept-pad",
+		Content:   "ENCRYPTED-STUFF-HERE",
+		NewProof:  "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		ExpiresAt: time.Now().Unix() - 60,
+	}
+
+	// Expired pads can't be subscribed to, so this one is swept as if the sweep ran later.
+	subscribed := model.Pad{
+		ID:        "swept-subscribed",
+		Content:   "ENCRYPTED-STUFF-HERE",
+		NewProof:  "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		ExpiresAt: time.Now().Unix() + 30,
+	}
+
+	live := model.Pad{
+		ID:        "unswept-pad",
+		Content:   "ENCRYPTED-STUFF-HERE",
+		NewProof:  "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		ExpiresAt: time.Now().Unix() + 60,
+	}
+
+	for _, body := range []model.Pad{expired, subscribed, live} {
+		err := pad.Remove(body.ID)
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		err = pad.Insert(body)
+		if err != nil {
+			t.Error(err.Error())
+		}
+	}
+
+	url := "ws" + strings.TrimPrefix(baseURL, "http") + "pad/" + subscribed.ID + "/subscribe"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(timeout))
+
+	err = pad.RemoveExpired(time.Now().Unix() + 45)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	// Check the store directly, as FromID hides expired pads anyway.
+	for _, id := range []string{expired.ID, subscribed.ID} {
+		_, err = pad.Store.FromID(id)
+		if err != sql.ErrNoRows {
+			t.Errorf("remove expired: expired pad %v was not removed (%v)", id, err)
+			return
+		}
+	}
+
+	_, err = pad.Store.FromID(live.ID)
+	if err != nil {
+		t.Errorf("remove expired: pad which hasn't expired was removed (%v)", err)
+		return
+	}
+
+	var event model.Event
+	err = conn.ReadJSON(&event)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if event.Type != pad.EventDeleted {
+		t.Errorf("remove expired: unexpected event %+v", event)
+	}
+
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("remove expired: subscription wasn't closed (%v)", err)
+		return
+	}
+
+	t.Log("remove expired: success")
+}
@@ -0,0 +1,129 @@
+package v1
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// formatRequest sends a request with a body and Accept header, returning the raw response body.
+func formatRequest(client *http.Client, method, url, contentType, accept string, body []byte) (res *http.Response, output []byte, err error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	res, err = client.Do(req)
+	if err != nil {
+		return
+	}
+
+	defer res.Body.Close()
+
+	output, err = ioutil.ReadAll(res.Body)
+	return
+}
+
+// formats tests creating a pad with CBOR and raw binary content, then getting it as MessagePack and raw bytes.
+func formats(t *testing.T, client *http.Client) {
+	id := "binary-pad"
+	content := []byte{0x00, 0xff, 0xfe, 0x80, 'E', 'N', 'C'}
+
+	err := pad.Remove(id)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	// The content is sent as a CBOR byte string, which isn't valid as a string.
+	body, err := cbor.Marshal(map[string]interface{}{
+		"ID":       id,
+		"Content":  content,
+		"NewProof": "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	res, _, err := formatRequest(client, http.MethodPut, baseURL+"pad", "application/cbor", "", body)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("formats: could not put cbor pad (%v)", res.Status)
+		return
+	}
+
+	res, body, err = formatRequest(client, http.MethodGet, baseURL+"pad/"+id, "", "application/msgpack", nil)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "application/msgpack" {
+		t.Errorf("formats: could not get msgpack pad (%v, %v)", res.Status, res.Header.Get("Content-Type"))
+		return
+	}
+
+	var output model.Pad
+	dec := msgpack.NewDecoder(bytes.NewReader(body))
+	dec.SetCustomStructTag("json")
+
+	err = dec.Decode(&output)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if output.ID != id || output.Content != model.Ciphertext(content) {
+		t.Errorf("formats: msgpack pad doesn't match (%q, %q)", output.ID, output.Content)
+	}
+
+	// Clients which prefer raw bytes get only the content.
+	res, body, err = formatRequest(client, http.MethodGet, baseURL+"pad/"+id, "", "application/octet-stream, application/json;q=0.5", nil)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK || !bytes.Equal(body, content) {
+		t.Errorf("formats: raw content doesn't match (%v, %q)", res.Status, body)
+	}
+
+	// Clients which accept anything still get JSON.
+	res, _, err = formatRequest(client, http.MethodGet, baseURL+"pad/"+id, "", "*/*", nil)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("formats: pad accepting anything wasn't json (%v)", res.Header.Get("Content-Type"))
+	}
+
+	res, _, err = formatRequest(client, http.MethodPut, baseURL+"pad", "text/plain", "", []byte("ENCRYPTED-STUFF-HERE"))
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("formats: unsupported body was accepted (%v)", res.Status)
+	}
+}
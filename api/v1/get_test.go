@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
 )
 
 // get tests a valid get request.
@@ -14,12 +15,28 @@ func get(t *testing.T, client *http.Client) {
 	}
 
 	expected := model.Pad{
-		ID:      "test",
-		Content: "ENCRYPTED-STUFF-HERE",
+		ID:       "test",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		Revision: 1,
+		Size:     20,
 	}
 
 	output := &model.Pad{}
 
+	err := pad.Remove(body.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(model.Pad{
+		ID:       "test",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	})
+	if err != nil {
+		t.Error(err.Error())
+	}
+
 	res, err, errorResponse := getRequest(t, client, output, baseURL+"pad/"+body.ID)
 	if err != nil {
 		t.Error(err.Error())
@@ -35,6 +52,11 @@ func get(t *testing.T, client *http.Client) {
 		return
 	}
 
+	if res.Header.Get("ETag") != `"1"` {
+		t.Errorf("get pad: expected etag of revision 1, got %v (%v, %v)", res.Header.Get("ETag"), res.Status, errorResponse.Error)
+		return
+	}
+
 	t.Logf("get pad: success (%v, %v)", res.Status, errorResponse.Error)
 }
 
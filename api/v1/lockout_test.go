@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+)
+
+// lockoutResponse is an error response along with a pad's lockout.
+type lockoutResponse struct {
+	Error   string
+	Lockout model.Lockout
+}
+
+// lockout tests a pad is locked after too many incorrect proofs, even against the correct proof, and unlocked by a correct one afterwards.
+func lockout(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "locked-out",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	incorrect := model.Pad{
+		ID:      original.ID,
+		Content: "OTHER-ENCRYPTED-STUFF-HERE",
+		Proof:   "OTHER-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	correct := incorrect
+	correct.Proof = original.NewProof
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	// The test config locks pads after 5 incorrect proofs.
+	for i := 1; i <= 5; i++ {
+		var output lockoutResponse
+		res, err, _ := request(t, client, incorrect, &output, http.MethodPut, baseURL+"pad")
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != http.StatusForbidden || output.Lockout.Failures != i || (output.Lockout.LockedUntil != 0) != (i == 5) {
+			t.Errorf("lockout: incorrect proof %v had the wrong lockout (%v, %+v)", i, res.Status, output)
+			return
+		}
+	}
+
+	var output lockoutResponse
+	res, err, _ := request(t, client, correct, &output, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusTooManyRequests || res.Header.Get("Retry-After") == "" || output.Lockout.LockedUntil == 0 {
+		t.Errorf("lockout: locked pad accepted a proof (%v, %+v)", res.Status, output)
+		return
+	}
+
+	// Pretend the lockout has ended.
+	err = pad.Store.LockPad(original.ID, 0)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	res, err, errorResponse := request(t, client, correct, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("lockout: correct proof failed after the lockout ended (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	_, err = pad.Store.Lockout(original.ID)
+	if err != sql.ErrNoRows {
+		t.Errorf("lockout: correct proof didn't reset the lockout (%v)", err)
+	}
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var (
+	cborDecMode, _ = cbor.DecOptions{
+		ByteStringToString: cbor.ByteStringToStringAllowed,
+	}.DecMode()
+)
+
+// Ciphertext is encrypted content, which binary formats send as raw bytes rather than as a string.
+// JSON can't send raw bytes, so JSON clients still have to encode it in a string themselves.
+type Ciphertext string
+
+// MarshalCBOR encodes ciphertext as a CBOR byte string.
+func (c Ciphertext) MarshalCBOR() ([]byte, error) {
+	return cbor.Marshal([]byte(c))
+}
+
+// UnmarshalCBOR decodes ciphertext from a CBOR byte string, or a text string.
+func (c *Ciphertext) UnmarshalCBOR(data []byte) error {
+	var s string
+	err := cborDecMode.Unmarshal(data, &s)
+	*c = Ciphertext(s)
+	return err
+}
+
+// EncodeMsgpack encodes ciphertext as MessagePack binary.
+func (c Ciphertext) EncodeMsgpack(enc *msgpack.Encoder) error {
+	return enc.EncodeBytes([]byte(c))
+}
+
+// DecodeMsgpack decodes ciphertext from MessagePack binary, or a string.
+func (c *Ciphertext) DecodeMsgpack(dec *msgpack.Decoder) error {
+	s, err := dec.DecodeString()
+	*c = Ciphertext(s)
+	return err
+}
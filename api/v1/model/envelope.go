@@ -0,0 +1,224 @@
+package model
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+)
+
+// EnvelopeMagic starts every envelope, so envelopes can be told apart from content written before them.
+const EnvelopeMagic = "CPAD"
+
+// Envelope versions are the encryption schemes content can be encrypted with.
+const (
+	// EnvelopeCBC is AES-256-CBC, as described in the README, with an HMAC-SHA256 of the IV and ciphertext.
+	EnvelopeCBC = 1
+
+	// EnvelopeGCM is AES-256-GCM, whose tag authenticates the ciphertext.
+	EnvelopeGCM = 2
+)
+
+// KDFs are the functions used to derive an envelope's key from a password.
+const (
+	// KDFPBKDF2 is PBKDF2 with HMAC-SHA256. Its parameter is the iteration count.
+	KDFPBKDF2 = 1
+
+	// KDFArgon2id is Argon2id. Its parameters are the time, memory in KiB, and threads.
+	KDFArgon2id = 2
+)
+
+var (
+	// ErrNoEnvelope is returned when content isn't in an envelope at all, as it was written before envelopes.
+	ErrNoEnvelope = errors.New("content is not in an envelope")
+
+	errEnvelopeTruncated = errors.New("envelope is truncated")
+	errEnvelopeVersion   = errors.New("envelope version is unknown")
+	errEnvelopeKDF       = errors.New("envelope key derivation function is unknown")
+	errEnvelopeKDFParams = errors.New("envelope key derivation parameters are too weak")
+	errEnvelopeSalt      = errors.New("envelope salt must be between 16 and 64 bytes")
+	errEnvelopeBlocks    = errors.New("envelope ciphertext must be a whole number of blocks")
+)
+
+var (
+	// SaltLen is the range of salt lengths an envelope can have.
+	SaltLen = MinMax{
+		Min: 16,
+		Max: 64,
+	}
+
+	// encodedMagic is how base64 encoded envelopes start, as the first 3 bytes of the magic always encode the same.
+	encodedMagic = []byte(base64.StdEncoding.EncodeToString([]byte(EnvelopeMagic))[:4])
+
+	// MinPBKDF2Iterations is the fewest PBKDF2 iterations an envelope can use.
+	MinPBKDF2Iterations uint32 = 10000
+)
+
+// Envelope is encrypted content along with everything, except the password, needed to decrypt it.
+// It is encoded as the magic, version, KDF, KDF parameters, salt length, salt, IV (or nonce), ciphertext and MAC (or tag).
+// Integers are big endian, and everything but the salt is a fixed length for the version, so the ciphertext is what's left.
+type Envelope struct {
+	Version byte
+	KDF     byte
+
+	// Iterations is the PBKDF2 iteration count.
+	Iterations uint32
+
+	// Time, Memory (in KiB) and Threads are the Argon2id parameters.
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+
+	Salt, IV, Ciphertext, MAC []byte
+}
+
+// ivLen and macLen are the lengths of an envelope version's IV (or nonce) and MAC (or tag).
+func ivLen(version byte) int {
+	if version == EnvelopeGCM {
+		return 12
+	}
+
+	return 16
+}
+
+func macLen(version byte) int {
+	if version == EnvelopeGCM {
+		return 16
+	}
+
+	return 32
+}
+
+// Bytes encodes an envelope.
+func (env Envelope) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(EnvelopeMagic)
+	buf.WriteByte(env.Version)
+	buf.WriteByte(env.KDF)
+
+	switch env.KDF {
+	case KDFPBKDF2:
+		binary.Write(&buf, binary.BigEndian, env.Iterations)
+	case KDFArgon2id:
+		binary.Write(&buf, binary.BigEndian, env.Time)
+		binary.Write(&buf, binary.BigEndian, env.Memory)
+		buf.WriteByte(env.Threads)
+	}
+
+	buf.WriteByte(byte(len(env.Salt)))
+	buf.Write(env.Salt)
+	buf.Write(env.IV)
+	buf.Write(env.Ciphertext)
+	buf.Write(env.MAC)
+	return buf.Bytes()
+}
+
+// ParseEnvelope checks the structure of an encoded envelope, without decrypting it.
+func ParseEnvelope(data []byte) (env Envelope, err error) {
+	if !bytes.HasPrefix(data, []byte(EnvelopeMagic)) {
+		err = ErrNoEnvelope
+		return
+	}
+
+	data = data[len(EnvelopeMagic):]
+
+	// next takes the next n bytes of the envelope.
+	next := func(n int) (b []byte) {
+		if err != nil || len(data) < n {
+			err = errEnvelopeTruncated
+			return nil
+		}
+
+		b, data = data[:n], data[n:]
+		return
+	}
+
+	header := next(2)
+	if err != nil {
+		return
+	}
+
+	env.Version, env.KDF = header[0], header[1]
+	if env.Version != EnvelopeCBC && env.Version != EnvelopeGCM {
+		err = errEnvelopeVersion
+		return
+	}
+
+	switch env.KDF {
+	case KDFPBKDF2:
+		params := next(4)
+		if err != nil {
+			return
+		}
+
+		env.Iterations = binary.BigEndian.Uint32(params)
+		if env.Iterations < MinPBKDF2Iterations {
+			err = errEnvelopeKDFParams
+			return
+		}
+	case KDFArgon2id:
+		params := next(9)
+		if err != nil {
+			return
+		}
+
+		env.Time = binary.BigEndian.Uint32(params)
+		env.Memory = binary.BigEndian.Uint32(params[4:])
+		env.Threads = params[8]
+
+		// Argon2 needs at least 8 KiB of memory per thread.
+		if env.Time < 1 || env.Threads < 1 || env.Memory < 8*uint32(env.Threads) {
+			err = errEnvelopeKDFParams
+			return
+		}
+	default:
+		err = errEnvelopeKDF
+		return
+	}
+
+	saltLen := next(1)
+	if err != nil {
+		return
+	}
+
+	env.Salt = next(int(saltLen[0]))
+	env.IV = next(ivLen(env.Version))
+	if err != nil {
+		return
+	}
+
+	if !SaltLen.Check(string(env.Salt)) {
+		err = errEnvelopeSalt
+		return
+	}
+
+	if len(data) < macLen(env.Version) {
+		err = errEnvelopeTruncated
+		return
+	}
+
+	split := len(data) - macLen(env.Version)
+	env.Ciphertext, env.MAC = data[:split], data[split:]
+
+	// CBC ciphertext is padded to whole blocks, so always has at least one.
+	if env.Version == EnvelopeCBC && (len(env.Ciphertext) == 0 || len(env.Ciphertext)%16 != 0) {
+		err = errEnvelopeBlocks
+	}
+
+	return
+}
+
+// EnvelopeOf checks the structure of the envelope content is in.
+// Clients which can only send strings, such as JSON clients, send envelopes base64 encoded.
+func EnvelopeOf(content Ciphertext) (Envelope, error) {
+	data := []byte(content)
+
+	if bytes.HasPrefix(data, encodedMagic) {
+		decoded, err := base64.StdEncoding.DecodeString(string(content))
+		if err == nil {
+			data = decoded
+		}
+	}
+
+	return ParseEnvelope(data)
+}
@@ -9,14 +9,177 @@ var (
 		Min: 0,
 		Max: 65535,
 	}
-	ProofLen = 32
+	ProofLen      = 32
+	EditorNameLen = MinMax{
+		Min: 1,
+		Max: 32,
+	}
 )
 
 type Pad struct {
-	ID       string `json:",omitempty"`
-	Content  string `json:",omitempty"`
-	Proof    string `json:",omitempty"`
-	NewProof string `json:",omitempty"`
+	ID       string     `json:",omitempty"`
+	Content  Ciphertext `json:",omitempty"`
+	Proof    string     `json:",omitempty"`
+	NewProof string     `json:",omitempty"`
+	Revision int64      `json:",omitempty"`
+
+	// ExpiresAt is when the pad expires as a unix timestamp, or 0 if it never expires.
+	// TTL can be sent instead to expire the pad that many seconds from now.
+	ExpiresAt int64 `json:",omitempty"`
+	TTL       int64 `json:",omitempty"`
+
+	// BurnAfterReading pads are removed by the first successful get.
+	BurnAfterReading bool `json:",omitempty"`
+
+	// PublicKey is a base64 encoded Ed25519 key, registered with NewPublicKey, which can sign for the pad.
+	// A signed challenge (Challenge and Response) or a Signature of the change can be sent instead of the proof.
+	// Pads can be created with only a public key, in which case they have no proof.
+	PublicKey    string `json:",omitempty"`
+	NewPublicKey string `json:",omitempty"`
+	Challenge    string `json:",omitempty"`
+	Response     string `json:",omitempty"`
+	Signature    string `json:",omitempty"`
+
+	// ReadToken is the hash of the token needed to get the pad, set with NewReadToken, or empty if anyone can.
+	ReadToken    string `json:",omitempty"`
+	NewReadToken string `json:",omitempty"`
+
+	// Editor is the name of the editor whose proof is being sent, rather than the owner's.
+	// Editors can only update the content of a pad.
+	Editor string `json:",omitempty"`
+
+	// Compacted is the sequence of the last op compacted into the content.
+	// Updates send it to compact ops into the new content, which removes them from the log.
+	Compacted int64 `json:",omitempty"`
+
+	// Sequence is the sequence an op is expected to be given, when appending an op.
+	Sequence int64 `json:",omitempty"`
+
+	// Chunks is how many chunks the content was uploaded in, or 0 if it is sent inline.
+	// Size is the size of the content (or all of its chunks) in bytes.
+	Chunks int   `json:",omitempty"`
+	Size   int64 `json:",omitempty"`
+
+	// Upload is the ID a client chooses for an upload it is starting, and Hashes the hex SHA-256 hash of each of its chunks
+	// in order, separated by commas, which it must send when signing.
+	Upload string `json:",omitempty"`
+	Hashes string `json:",omitempty"`
+
+	// Envelope is the version of the envelope the content is in, or 0 if it isn't in one, so clients can migrate schemes.
+	Envelope int `json:",omitempty"`
+
+	// Reservation is the token of the reservation held on the ID, when creating a reserved pad.
+	Reservation string `json:",omitempty"`
+
+	// Work is a proof of work challenge, and Nonce its solution, when creating a pad on a server which requires them.
+	Work  string `json:",omitempty"`
+	Nonce string `json:",omitempty"`
+}
+
+// Rotation is a pad re-encrypted with a new key, along with everything else which was encrypted with the old one.
+type Rotation struct {
+	Pad
+
+	// Revisions is the re-encrypted content of every kept revision, by revision.
+	Revisions map[int64]Ciphertext `json:",omitempty"`
+
+	// Attachments is every re-encrypted attachment, by ID.
+	Attachments map[string][]byte `json:",omitempty"`
+
+	// Public removes a pad's read token, so anyone can read it, rather than sending a NewReadToken.
+	Public bool `json:",omitempty"`
+}
+
+// Availability is whether a pad ID can be used to create a new pad.
+type Availability struct {
+	ID        string
+	Available bool
+}
+
+// Reservation holds an unused pad ID for the client with the token until it expires.
+type Reservation struct {
+	ID        string
+	Token     string `json:",omitempty"`
+	ExpiresAt int64  `json:",omitempty"`
+}
+
+// Editor is a named proof which can update the content of a pad.
+type Editor struct {
+	Name  string
+	Proof string `json:",omitempty"`
+}
+
+// Lockout is how many times in a row a pad has been sent an incorrect proof, and when it is locked until because of them.
+type Lockout struct {
+	Failures    int
+	LockedUntil int64 `json:",omitempty"`
+}
+
+// Usage is how many pads are stored, and how many bytes their content, attachments and staged uploads take up.
+type Usage struct {
+	Pads  int64
+	Bytes int64
+}
+
+// Challenge is a single use challenge issued for a pad.
+type Challenge struct {
+	Challenge string
+	ExpiresAt int64
+}
+
+// Revision is a summary of a stored revision of a pad.
+type Revision struct {
+	Revision, Created int64
+}
+
+// Op is an encrypted operation in a pad's op log.
+type Op struct {
+	Sequence int64
+	Content  Ciphertext `json:",omitempty"`
+	Created  int64
+}
+
+// Upload is an upload of a pad's content in chunks, which replaces the content once committed.
+// The ID is a secret which authorises uploading the chunks, so is only given to the client which started it.
+type Upload struct {
+	ID        string `json:",omitempty"`
+	Pad       string
+	Revision  int64
+	Chunks    int
+	Size      int64
+	ExpiresAt int64
+
+	// Hashes are the hex SHA-256 hashes each chunk must have, separated by commas, if the upload was started with them.
+	Hashes string `json:",omitempty"`
+
+	// Received is the chunks which have been uploaded so far.
+	Received []Chunk `json:",omitempty"`
+}
+
+// Chunk is a summary of an uploaded chunk.
+type Chunk struct {
+	Index int
+	Size  int64
+	Hash  string
+}
+
+// Attachment is a summary of an encrypted file attached to a pad.
+type Attachment struct {
+	ID      string
+	Size    int64
+	Created int64
+}
+
+// Event is a change to a pad sent to its subscribers.
+type Event struct {
+	// Type is either updated, deleted or op.
+	Type string
+
+	ID       string
+	Revision int64      `json:",omitempty"`
+	Sequence int64      `json:",omitempty"`
+	Content  Ciphertext `json:",omitempty"`
+	Chunks   int        `json:",omitempty"`
 }
 
 // MinMax is a simple struct representing a minimum and maximum length for a string.
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"crypto/sha256"
+	"math/bits"
+)
+
+// MaxNonceLen is the longest nonce a proof of work solution can have.
+const MaxNonceLen = 64
+
+// Work is a proof of work challenge, which is solved by finding a nonce where the SHA-256 hash of
+// "{challenge}:{nonce}" starts with at least Difficulty zero bits.
+type Work struct {
+	Challenge  string `json:",omitempty"`
+	Difficulty int
+	ExpiresAt  int64 `json:",omitempty"`
+}
+
+// Solves checks if a nonce solves a proof of work challenge.
+func (work Work) Solves(nonce string) bool {
+	if len(nonce) > MaxNonceLen {
+		return false
+	}
+
+	hash := sha256.Sum256([]byte(work.Challenge + ":" + nonce))
+
+	zeros := 0
+	for _, b := range hash {
+		zeros += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+
+	return zeros >= work.Difficulty
+}
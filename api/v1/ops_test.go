@@ -0,0 +1,184 @@
+package v1
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+)
+
+// appendOp appends an op to a pad, returning the response status and the op's sequence.
+func appendOp(t *testing.T, client *http.Client, data model.Pad) (status int, sequence int64) {
+	output := &model.Op{}
+
+	res, err, _ := request(t, client, data, output, http.MethodPost, baseURL+"pad/"+data.ID+"/ops")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	return res.StatusCode, output.Sequence
+}
+
+// ops tests appending ops to a pad's log, listing them, and compacting them into the content.
+func ops(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "ops-pad",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	res, err, errorResponse := request(t, client, original, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("ops: could not put new pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	op := model.Pad{
+		ID:      original.ID,
+		Content: "ENCRYPTED-OP-1",
+		Proof:   original.NewProof,
+	}
+
+	status, sequence := appendOp(t, client, op)
+	if status != http.StatusCreated || sequence != 1 {
+		t.Errorf("ops: could not append first op (%v, %v)", status, sequence)
+		return
+	}
+
+	// The client expects to append the first op, but it already has been.
+	op.Content = "ENCRYPTED-OP-2"
+	op.Sequence = 1
+
+	status, _ = appendOp(t, client, op)
+	if status != http.StatusConflict {
+		t.Errorf("ops: stale op was appended (%v)", status)
+	}
+
+	op.Sequence = 2
+
+	status, sequence = appendOp(t, client, op)
+	if status != http.StatusCreated || sequence != 2 {
+		t.Errorf("ops: could not append second op (%v, %v)", status, sequence)
+		return
+	}
+
+	op.Sequence = 0
+	op.Proof = "INCORRECT-PROOF-ABCDEFGHIJKLMNOP"
+
+	status, _ = appendOp(t, client, op)
+	if status != http.StatusForbidden {
+		t.Errorf("ops: op was appended with an incorrect proof (%v)", status)
+	}
+
+	var log []model.Op
+	res, err, errorResponse = getRequest(t, client, &log, baseURL+"pad/"+original.ID+"/ops")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK || len(log) != 2 || log[0].Content != "ENCRYPTED-OP-1" || log[1].Content != "ENCRYPTED-OP-2" {
+		t.Errorf("ops: unexpected op log (%v, %v, %+v)", res.Status, errorResponse.Error, log)
+		return
+	}
+
+	// Compact the first op into the content.
+	compacted := model.Pad{
+		ID:        original.ID,
+		Content:   "COMPACTED-ENCRYPTED-STUFF",
+		Proof:     original.NewProof,
+		Compacted: 1,
+	}
+
+	res, err, errorResponse = request(t, client, compacted, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("ops: could not compact ops (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	output := &model.Pad{}
+	_, err, _ = getRequest(t, client, output, baseURL+"pad/"+original.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if output.Compacted != 1 {
+		t.Errorf("ops: pad was compacted to %v, not 1", output.Compacted)
+	}
+
+	log = nil
+	_, err, _ = getRequest(t, client, &log, baseURL+"pad/"+original.ID+"/ops")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(log) != 1 || log[0].Sequence != 2 {
+		t.Errorf("ops: compacted ops were kept %+v", log)
+	}
+
+	// Compacted ops can't be listed.
+	res, err, _ = getRequest(t, client, nil, baseURL+"pad/"+original.ID+"/ops?since=0")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusGone {
+		t.Errorf("ops: compacted ops were listed (%v)", res.Status)
+	}
+
+	// Ops which haven't been appended yet can't be compacted.
+	compacted.Compacted = 3
+
+	res, err, _ = request(t, client, compacted, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("ops: compacted ops which weren't appended (%v)", res.Status)
+	}
+}
+
+// opsFull tests that the op log stops growing until it is compacted.
+func opsFull(t *testing.T, client *http.Client) {
+	op := model.Pad{
+		ID:      "ops-pad",
+		Content: "ENCRYPTED-OP",
+		Proof:   "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	// The test config allows 3 uncompacted ops, and there is already 1.
+	for i := 0; i < 2; i++ {
+		status, _ := appendOp(t, client, op)
+		if status != http.StatusCreated {
+			t.Errorf("ops full: could not append op (%v)", status)
+			return
+		}
+	}
+
+	status, _ := appendOp(t, client, op)
+	if status != http.StatusConflict {
+		t.Errorf("ops full: op was appended to a full log (%v)", status)
+	}
+}
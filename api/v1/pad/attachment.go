@@ -0,0 +1,256 @@
+package pad
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+	"github.com/gorilla/mux"
+)
+
+const (
+	attachmentIDLen = 16
+)
+
+var (
+	errNotOctetStream     = errors.New("attachments must be sent as application/octet-stream")
+	errAttachmentTooLarge = errors.New("attachment is larger than the maximum size")
+	errEmptyAttachment    = errors.New("attachments can not be empty")
+	errTooManyAttachments = errors.New("pad already has the maximum number of attachments")
+	errAttachmentExists   = errors.New("pad already has, or had, an attachment with the id")
+	errInvalidAttachment  = fmt.Errorf("attachment ids must be %v base64url encoded bytes", attachmentIDLen)
+	errNoAttachmentID     = errors.New("signed attachments must choose their id in x-attachment")
+	errBurnAttachments    = errors.New("burn after reading pads can not have attachments")
+)
+
+// Attachments lists the attachments of a pad.
+func Attachments(w http.ResponseWriter, r *http.Request) {
+	pad, ok := attachmentPad(w, r, true)
+	if !ok {
+		return
+	}
+
+	attachments, err := Store.Attachments(pad.ID)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	if attachments == nil {
+		attachments = []model.Attachment{}
+	}
+
+	helper.Response(attachments, http.StatusOK, w)
+}
+
+// Attachment downloads an encrypted attachment of a pad as raw bytes.
+func Attachment(w http.ResponseWriter, r *http.Request) {
+	pad, ok := attachmentPad(w, r, true)
+	if !ok {
+		return
+	}
+
+	_, data, err := Store.Attachment(pad.ID, mux.Vars(r)["attachment"])
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// PostAttachment attaches an encrypted file, sent as the raw request body, to a pad.
+// As the body isn't JSON, the proof (or other authorisation) is sent in headers.
+func PostAttachment(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/octet-stream" {
+		helper.ThrowErr(errNotOctetStream, http.StatusUnsupportedMediaType, w)
+		return
+	}
+
+	pad, ok := attachmentPad(w, r, false)
+	if !ok {
+		return
+	}
+
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, cfg.MaxAttachmentSize))
+	if err != nil {
+		helper.ThrowErr(errAttachmentTooLarge, http.StatusRequestEntityTooLarge, w)
+		return
+	}
+
+	if len(data) == 0 {
+		helper.ThrowErr(errEmptyAttachment, http.StatusBadRequest, w)
+		return
+	}
+
+	auth := headerAuth(r)
+
+	// Clients can choose the attachment's ID, and must when signing, so a signature can only add one attachment.
+	id, err := attachmentID(r.Header.Get("X-Attachment"), auth.Signature != "")
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
+		return
+	}
+
+	// Both the owner and editors can attach files, as they only change the content.
+	_, err = authorise(pad, auth, attachmentMessage(pad.ID, id, data, pad.Revision))
+	if err != nil {
+		throwAuthErr(err, w)
+		return
+	}
+
+	// Nothing can be added while the server is nearly full.
+	err = checkCapacity()
+	if err != nil {
+		helper.ThrowErr(err, quotaErrStatus(err), w)
+		return
+	}
+
+	attachment := model.Attachment{
+		ID:      id,
+		Size:    int64(len(data)),
+		Created: time.Now().Unix(),
+	}
+
+	err = Store.PutAttachment(pad.ID, attachment, data, cfg.MaxAttachments)
+	if err != nil {
+		if err == errTooManyAttachments || err == errAttachmentExists {
+			helper.ThrowErr(err, http.StatusConflict, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	helper.Response(attachment, http.StatusCreated, w)
+}
+
+// attachmentID checks an attachment ID chosen by the client, or generates one if it didn't choose one and isn't signing.
+func attachmentID(chosen string, signed bool) (id string, err error) {
+	if chosen != "" {
+		decoded, decodeErr := base64.RawURLEncoding.DecodeString(chosen)
+		if decodeErr != nil || len(decoded) != attachmentIDLen {
+			err = errInvalidAttachment
+			return
+		}
+
+		id = chosen
+		return
+	}
+
+	if signed {
+		err = errNoAttachmentID
+		return
+	}
+
+	generated := make([]byte, attachmentIDLen)
+	_, err = rand.Read(generated)
+	if err != nil {
+		return
+	}
+
+	id = base64.RawURLEncoding.EncodeToString(generated)
+	return
+}
+
+// DeleteAttachment removes an attachment from a pad, authorised in headers like attaching it.
+func DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	pad, ok := attachmentPad(w, r, false)
+	if !ok {
+		return
+	}
+
+	id := mux.Vars(r)["attachment"]
+
+	_, err := authorise(pad, headerAuth(r), removeAttachmentMessage(pad.ID, id, pad.Revision))
+	if err != nil {
+		throwAuthErr(err, w)
+		return
+	}
+
+	_, _, err = Store.Attachment(pad.ID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	err = Store.RemoveAttachment(pad.ID, id)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// attachmentPad gets and validates the pad a request for its attachments is for.
+// Reads must send the pad's read token, and writes must send something to authorise them with.
+// If it isn't valid, an error is sent and ok is false.
+func attachmentPad(w http.ResponseWriter, r *http.Request, read bool) (pad model.Pad, ok bool) {
+	id := mux.Vars(r)["id"]
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(id) {
+		helper.ThrowErr(errInvalidIDLen, http.StatusBadRequest, w)
+		return
+	}
+
+	if !read {
+		// Check if the proof is a valid length, or there is a challenge response instead.
+		err := checkAuthLen(headerAuth(r))
+		if err != nil {
+			helper.ThrowErr(err, http.StatusBadRequest, w)
+			return
+		}
+	}
+
+	pad, err := FromID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	// Otherwise attachments could be read without burning the pad.
+	if pad.BurnAfterReading {
+		helper.ThrowErr(errBurnAttachments, http.StatusForbidden, w)
+		return
+	}
+
+	if read {
+		err = authoriseRead(pad, r)
+		if err != nil {
+			helper.ThrowErr(err, http.StatusForbidden, w)
+			return
+		}
+	}
+
+	ok = true
+	return
+}
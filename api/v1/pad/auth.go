@@ -0,0 +1,113 @@
+package pad
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+)
+
+// authorise checks that a request proves it may write to a pad, and whether it is from the owner or an editor.
+// The owner can use the proof, answer a challenge, or sign the change it makes. Editors use their own proof.
+// Incorrect proofs count towards locking the pad, and are returned as a *lockoutError along with its lockout.
+func authorise(pad, data model.Pad, signed []byte) (owner bool, err error) {
+	lockout, err := checkLockout(pad.ID)
+	if err != nil {
+		return
+	}
+
+	owner, err = prove(pad, data, signed)
+	switch {
+	case err == nil && lockout.Failures > 0:
+		err = Store.RemoveLockout(pad.ID)
+	case isIncorrectProof(err):
+		err = fail(pad.ID, err)
+	}
+
+	return
+}
+
+// prove checks the proof, editor proof, challenge response or signature of a request.
+func prove(pad, data model.Pad, signed []byte) (owner bool, err error) {
+	if data.Editor != "" {
+		var editor model.Editor
+		editor, err = Store.Editor(pad.ID, data.Editor)
+		if err != nil && err != sql.ErrNoRows {
+			return
+		}
+
+		// Unknown editors fail the same way as incorrect proofs, so editor names can't be guessed.
+		if err == sql.ErrNoRows || !VerifyProof(editor.Proof, data.Proof) {
+			err = errIncorrectEditorProof
+		}
+
+		return
+	}
+
+	owner = true
+
+	if data.Signature != "" {
+		err = verifySignature(pad, signed, data.Signature)
+		return
+	}
+
+	if data.Challenge != "" {
+		err = answerChallenge(pad, data.Challenge, data.Response)
+		return
+	}
+
+	if !VerifyProof(pad.Proof, data.Proof) {
+		err = errIncorrectProof
+	}
+
+	return
+}
+
+// headerAuth gets the proof, editor, challenge response or signature of a request from its headers.
+// This is for requests whose body is raw data, rather than JSON with these fields.
+func headerAuth(r *http.Request) model.Pad {
+	return model.Pad{
+		Proof:     r.Header.Get("X-Proof"),
+		Editor:    r.Header.Get("X-Editor"),
+		Challenge: r.Header.Get("X-Challenge"),
+		Response:  r.Header.Get("X-Response"),
+		Signature: r.Header.Get("X-Signature"),
+	}
+}
+
+// checkAuthLen checks that a request has something to authorise it with.
+func checkAuthLen(data model.Pad) error {
+	if data.Signature != "" {
+		return nil
+	}
+
+	if data.Challenge != "" {
+		if data.Response == "" {
+			return errIncorrectResponse
+		}
+
+		return nil
+	}
+
+	if len(data.Proof) != model.ProofLen {
+		return errNoProof
+	}
+
+	return nil
+}
+
+// isAuthErr checks if an error is from a request failing to prove it may write to a pad.
+func isAuthErr(err error) bool {
+	var lockoutErr *lockoutError
+	if errors.As(err, &lockoutErr) {
+		return true
+	}
+
+	switch err {
+	case errIncorrectProof, errInvalidChallenge, errIncorrectResponse, errNoPublicKey, errIncorrectSignature, errIncorrectEditorProof, errNotOwner:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,32 @@
+package pad
+
+import (
+	"net/http"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+)
+
+const (
+	// bodyOverhead is room in a request body for everything other than the content.
+	bodyOverhead = 16 * 1024
+)
+
+var (
+	// maxBodySize is the largest body of a request with a pad: the longest content, which JSON escaping can
+	// make up to six times as long (each byte as \u00XX), and everything else.
+	maxBodySize = 6*int64(model.ContentLen.Max) + bodyOverhead
+)
+
+// decode decodes a request's body into v, refusing bodies larger than a request with a pad can be
+// rather than reading however much the client sends.
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	return helper.Decode(r, v)
+}
+
+// rotationBodySize is the largest body of a rotation: a pad for the content and each kept revision,
+// and every attachment, which JSON encodes in base64 (4/3 of its size).
+func rotationBodySize() int64 {
+	return int64(cfg.History+1)*maxBodySize + int64(cfg.MaxAttachments)*(cfg.MaxAttachmentSize*4/3+bodyOverhead)
+}
@@ -0,0 +1,250 @@
+package pad
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// readTokenHeader is the header a read token is sent in.
+	// Clients which can't set headers, such as browser event sources, can use the token query parameter.
+	readTokenHeader = "X-Read-Token"
+)
+
+var (
+	errIncorrectReadToken    = errors.New("read token is missing or incorrect")
+	errInvalidReadTokenLen   = fmt.Errorf("read tokens must be 0 or %v in length", model.ProofLen)
+	errInvalidEditorNameLen  = fmt.Errorf("editor names must be between %v and %v in length", model.EditorNameLen.Min, model.EditorNameLen.Max)
+	errNotOwner              = errors.New("only the owner of a pad can do that")
+	errIncorrectEditorProof  = errors.New("editor proofs do not match")
+	errInvalidEditorProofLen = fmt.Errorf("editor proofs must be %v in length", model.ProofLen)
+	errNoEditorChallenge     = errors.New("signed changes to editors must include a challenge")
+)
+
+// hashReadToken hashes a read token.
+// Read tokens are checked on every read, so they are random rather than slowly hashed like proofs.
+func hashReadToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// authoriseRead checks that a request may read a pad, by sending the read token if the pad has one.
+func authoriseRead(pad model.Pad, r *http.Request) error {
+	if pad.ReadToken == "" {
+		return nil
+	}
+
+	token := r.Header.Get(readTokenHeader)
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(pad.ReadToken), []byte(hashReadToken(token))) != 1 {
+		return errIncorrectReadToken
+	}
+
+	return nil
+}
+
+// ownerOnly checks if a change to a pad can only be made by its owner, rather than an editor.
+func ownerOnly(data model.Pad) bool {
+	return data.NewProof != "" ||
+		data.NewPublicKey != "" ||
+		data.NewReadToken != "" ||
+		data.ExpiresAt != 0 ||
+		data.BurnAfterReading
+}
+
+// Editors lists the names of a pad's editors.
+func Editors(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(id) {
+		helper.ThrowErr(errInvalidIDLen, http.StatusBadRequest, w)
+		return
+	}
+
+	pad, err := FromID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	err = authoriseRead(pad, r)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusForbidden, w)
+		return
+	}
+
+	editors, err := Store.Editors(id)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	// Only list the names, not the hashed proofs.
+	names := make([]model.Editor, 0, len(editors))
+	for _, editor := range editors {
+		names = append(names, model.Editor{
+			Name: editor.Name,
+		})
+	}
+
+	helper.Response(names, http.StatusOK, w)
+}
+
+// PutEditor adds an editor to a pad, or changes an editor's proof.
+// The new editor's proof is sent as the new proof, and the owner must authorise the request.
+func PutEditor(w http.ResponseWriter, r *http.Request) {
+	pad, data, name, ok := editorRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if len(data.NewProof) != model.ProofLen {
+		helper.ThrowErr(errInvalidEditorProofLen, http.StatusBadRequest, w)
+		return
+	}
+
+	owner, err := authorise(pad, data, editorMessage(pad.ID, name, data.NewProof, pad.Revision, data.Challenge))
+	if err == nil && !owner {
+		err = errNotOwner
+	}
+
+	if err != nil {
+		throwAuthErr(err, w)
+		return
+	}
+
+	hash, err := HashProof(data.NewProof)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	err = Store.PutEditor(pad.ID, model.Editor{
+		Name:  name,
+		Proof: hash,
+	})
+
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteEditor removes an editor from a pad, which the owner must authorise.
+func DeleteEditor(w http.ResponseWriter, r *http.Request) {
+	pad, data, name, ok := editorRequest(w, r)
+	if !ok {
+		return
+	}
+
+	owner, err := authorise(pad, data, removeEditorMessage(pad.ID, name, pad.Revision, data.Challenge))
+	if err == nil && !owner {
+		err = errNotOwner
+	}
+
+	if err != nil {
+		throwAuthErr(err, w)
+		return
+	}
+
+	err = Store.RemoveEditor(pad.ID, name)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// editorRequest gets and validates the pad, request body and editor name of a request to manage an editor.
+// If it isn't valid, an error is sent and ok is false.
+func editorRequest(w http.ResponseWriter, r *http.Request) (pad, data model.Pad, name string, ok bool) {
+	vars := mux.Vars(r)
+	name = vars["name"]
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(vars["id"]) {
+		helper.ThrowErr(errInvalidIDLen, http.StatusBadRequest, w)
+		return
+	}
+
+	// Check if the name is a valid length.
+	if !model.EditorNameLen.Check(name) {
+		helper.ThrowErr(errInvalidEditorNameLen, http.StatusBadRequest, w)
+		return
+	}
+
+	// Get data from the request, in whichever format it was sent.
+	err := decode(w, r, &data)
+	if err != nil {
+		helper.ThrowErr(err, helper.DecodeErrStatus(err), w)
+		return
+	}
+
+	// Check if the proof is a valid length, or there is a challenge response instead.
+	err = checkAuthLen(data)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
+		return
+	}
+
+	if data.Signature != "" && data.Challenge == "" {
+		helper.ThrowErr(errNoEditorChallenge, http.StatusBadRequest, w)
+		return
+	}
+
+	pad, err = FromID(vars["id"])
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	// Changing editors doesn't change the pad's revision, so signatures include a single use challenge instead,
+	// which stops them being replayed, such as to give a removed editor back their proof.
+	if data.Signature != "" {
+		err = useChallenge(pad.ID, data.Challenge)
+		if err != nil {
+			throwAuthErr(err, w)
+			return
+		}
+	}
+
+	ok = true
+	return
+}
+
+// editorMessage is the message an owner signs to give an editor a proof, with a challenge.
+func editorMessage(id, name, proof string, revision int64, challenge string) []byte {
+	hash := sha256.Sum256([]byte(proof))
+	return []byte(fmt.Sprintf("cryptopad-editor\n%v\n%v\n%v\n%v\n%v", id, name, hex.EncodeToString(hash[:]), revision, challenge))
+}
+
+// removeEditorMessage is the message an owner signs to remove an editor, with a challenge.
+func removeEditorMessage(id, name string, revision int64, challenge string) []byte {
+	return []byte(fmt.Sprintf("cryptopad-remove-editor\n%v\n%v\n%v\n%v", id, name, revision, challenge))
+}
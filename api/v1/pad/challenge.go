@@ -0,0 +1,169 @@
+package pad
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// challengeLifetime is how long a client has to answer a challenge.
+	challengeLifetime = time.Minute
+
+	challengeLen = 32
+)
+
+var (
+	errNoPublicKey       = errors.New("pad has no public key to answer challenges with")
+	errInvalidPublicKey  = errors.New("public keys must be base64 encoded ed25519 keys")
+	errInvalidChallenge  = errors.New("challenge is unknown, expired or already used")
+	errIncorrectResponse = errors.New("challenge response is incorrect")
+)
+
+// challenge is an issued challenge which hasn't been answered yet.
+type challenge struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+var (
+	challengesMutex sync.Mutex
+	challenges      = make(map[string]challenge)
+)
+
+// Challenge issues a single use challenge for a pad, which can be signed instead of sending the proof.
+func Challenge(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(id) {
+		helper.ThrowErr(errInvalidIDLen, http.StatusBadRequest, w)
+		return
+	}
+
+	pad, err := FromID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	if pad.PublicKey == "" {
+		helper.ThrowErr(errNoPublicKey, http.StatusBadRequest, w)
+		return
+	}
+
+	nonce := make([]byte, challengeLen)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	issued := challenge{
+		ID:        id,
+		ExpiresAt: time.Now().Add(challengeLifetime),
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(nonce)
+
+	challengesMutex.Lock()
+
+	// Forget any challenges which were never answered.
+	for key, other := range challenges {
+		if time.Now().After(other.ExpiresAt) {
+			delete(challenges, key)
+		}
+	}
+
+	challenges[encoded] = issued
+
+	challengesMutex.Unlock()
+
+	helper.Response(model.Challenge{
+		Challenge: encoded,
+		ExpiresAt: issued.ExpiresAt.Unix(),
+	}, http.StatusOK, w)
+}
+
+// answerChallenge checks a response to a challenge was signed by a pad's key.
+// Challenges are used up by any answer, right or wrong, so they can't be guessed at.
+func answerChallenge(pad model.Pad, nonce, response string) (err error) {
+	err = useChallenge(pad.ID, nonce)
+	if err != nil {
+		return
+	}
+
+	if pad.PublicKey == "" {
+		err = errNoPublicKey
+		return
+	}
+
+	key, err := decodePublicKey(pad.PublicKey)
+	if err != nil {
+		return
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(response)
+	if err != nil || !ed25519.Verify(key, challengeMessage(pad.ID, nonce), signature) {
+		err = errIncorrectResponse
+	}
+
+	return
+}
+
+// useChallenge uses up a challenge, checking it was issued for a pad and hasn't expired.
+func useChallenge(id, nonce string) (err error) {
+	challengesMutex.Lock()
+	issued, ok := challenges[nonce]
+	delete(challenges, nonce)
+	challengesMutex.Unlock()
+
+	if !ok || issued.ID != id || time.Now().After(issued.ExpiresAt) {
+		err = errInvalidChallenge
+	}
+
+	return
+}
+
+// forgetChallenges forgets every challenge issued for a pad, so they can't be answered.
+func forgetChallenges(id string) {
+	challengesMutex.Lock()
+	defer challengesMutex.Unlock()
+
+	for key, issued := range challenges {
+		if issued.ID == id {
+			delete(challenges, key)
+		}
+	}
+}
+
+// challengeMessage is the message a client signs to answer a challenge.
+func challengeMessage(id, nonce string) []byte {
+	return []byte("cryptopad-challenge\n" + id + "\n" + nonce)
+}
+
+// decodePublicKey decodes and checks a base64 encoded Ed25519 public key.
+func decodePublicKey(encoded string) (key ed25519.PublicKey, err error) {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(decoded) != ed25519.PublicKeySize {
+		err = errInvalidPublicKey
+		return
+	}
+
+	key = ed25519.PublicKey(decoded)
+	return
+}
@@ -2,16 +2,63 @@ package pad
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
-	"github.com/VolticFroogo/cryptopad-server/db"
+	"github.com/gchaincl/dotsql"
 )
 
+// SQLStore is a PadStore backed by a SQL database and its named queries.
+type SQLStore struct {
+	DB  *sql.DB
+	Dot *dotsql.DotSql
+
+	// History is how many revisions of each pad to keep.
+	History int
+}
+
+// NewSQLStore creates a SQLStore from a connection and its queries.
+func NewSQLStore(db *sql.DB, dot *dotsql.DotSql, history int) *SQLStore {
+	return &SQLStore{
+		DB:      db,
+		Dot:     dot,
+		History: history,
+	}
+}
+
+// CreateTables creates any missing tables used by the store.
+func (store *SQLStore) CreateTables() (err error) {
+	for _, query := range []string{
+		"v1-create-pad-table",
+		"v1-create-revision-table",
+		"v1-create-burnt-table",
+		"v1-create-editor-table",
+		"v1-create-op-table",
+		"v1-create-upload-table",
+		"v1-create-upload-chunk-table",
+		"v1-create-chunk-table",
+		"v1-create-attachment-table",
+		"v1-create-removed-attachment-table",
+		"v1-create-lockout-table",
+	} {
+		_, err = store.Dot.Exec(
+			store.DB,
+			query,
+		)
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
 // FromID gets a pad from the database given an ID.
-func FromID(id string) (pad model.Pad, err error) {
+func (store *SQLStore) FromID(id string) (pad model.Pad, err error) {
 	// Query a row from our ID.
-	row, err := db.Dot.QueryRow(
-		db.SQL,
+	row, err := store.Dot.QueryRow(
+		store.DB,
 		"v1-pad-from-id",
 		id,
 	)
@@ -26,39 +73,1197 @@ func FromID(id string) (pad model.Pad, err error) {
 }
 
 // Insert a pad into the database.
-func Insert(pad model.Pad) (err error) {
-	_, err = db.Dot.Exec(
-		db.SQL,
-		"v1-insert-pad",
-		pad.ID,
-		pad.Content,
-		pad.NewProof,
-	)
+// Content is bound as bytes, here and wherever else it is written, so it is stored as a BLOB just as it was sent.
+func (store *SQLStore) Insert(pad model.Pad) (err error) {
+	return store.transaction(func(tx *sql.Tx) (err error) {
+		_, err = store.Dot.Exec(
+			tx,
+			"v1-insert-pad",
+			pad.ID,
+			[]byte(pad.Content),
+			pad.NewProof,
+			pad.ExpiresAt,
+			pad.BurnAfterReading,
+			pad.PublicKey,
+			pad.ReadToken,
+			pad.Size,
+		)
 
-	return
+		if err != nil {
+			return
+		}
+
+		// The ID is being reused, so it is no longer burnt.
+		_, err = store.Dot.Exec(
+			tx,
+			"v1-remove-burnt",
+			pad.ID,
+		)
+
+		if err != nil {
+			return
+		}
+
+		return store.record(tx, pad.ID, 1, pad.Content)
+	})
+}
+
+// Update a pad in the database if it is still at the pad's revision.
+func (store *SQLStore) Update(pad model.Pad) (err error) {
+	return store.transaction(func(tx *sql.Tx) error {
+		return store.update(tx, pad)
+	})
 }
 
-// Update a pad in the database.
-func Update(pad model.Pad) (err error) {
-	_, err = db.Dot.Exec(
-		db.SQL,
+// Rotate replaces a pad along with its re-encrypted history and attachments, and removes its editors, ops and uploads.
+// If the pad has a revision or attachment which isn't being replaced, or ops which weren't compacted, nothing is changed.
+func (store *SQLStore) Rotate(pad model.Pad, revisions map[int64]model.Ciphertext, attachments map[string][]byte) (err error) {
+	return store.transaction(func(tx *sql.Tx) (err error) {
+		sequence, err := store.opSequence(tx, pad.ID)
+		if err != nil {
+			return
+		}
+
+		if sequence != pad.Compacted {
+			err = errStaleSequence
+			return
+		}
+
+		for revision, content := range revisions {
+			err = store.execOne(tx, "v1-update-revision", []byte(content), pad.ID, revision)
+			if err != nil {
+				return
+			}
+		}
+
+		err = store.count(tx, "v1-count-revisions", pad.ID, len(revisions))
+		if err != nil {
+			return
+		}
+
+		// Updating records the new content as a revision, so it must come after the history is replaced.
+		err = store.update(tx, pad)
+		if err != nil {
+			return
+		}
+
+		for id, data := range attachments {
+			err = store.execOne(tx, "v1-update-attachment", len(data), data, pad.ID, id)
+			if err != nil {
+				return
+			}
+		}
+
+		err = store.count(tx, "v1-count-attachments", pad.ID, len(attachments))
+		if err != nil {
+			return
+		}
+
+		for _, query := range []string{
+			"v1-remove-editors",
+			"v1-remove-ops",
+			"v1-remove-pad-upload-chunks",
+			"v1-remove-pad-uploads",
+		} {
+			_, err = store.Dot.Exec(
+				tx,
+				query,
+				pad.ID,
+			)
+
+			if err != nil {
+				return
+			}
+		}
+
+		return
+	})
+}
+
+// update updates a pad in a transaction if it is still at the pad's revision.
+func (store *SQLStore) update(tx *sql.Tx, pad model.Pad) (err error) {
+	res, err := store.Dot.Exec(
+		tx,
 		"v1-update-pad",
-		pad.Content,
+		[]byte(pad.Content),
 		pad.NewProof,
+		pad.ExpiresAt,
+		pad.BurnAfterReading,
+		pad.PublicKey,
+		pad.ReadToken,
+		pad.Compacted,
+		pad.Size,
 		pad.ID,
+		pad.Revision,
+	)
+
+	if err != nil {
+		return
+	}
+
+	// If no rows were updated, the pad was updated (or removed) since it was read.
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+
+	if rows == 0 {
+		err = errStaleRevision
+		return
+	}
+
+	// Ops which have been compacted into the content are no longer needed.
+	_, err = store.Dot.Exec(
+		tx,
+		"v1-prune-ops",
+		pad.ID,
+		pad.Compacted,
+	)
+
+	if err != nil {
+		return
+	}
+
+	// The content replaces any chunks the pad had.
+	_, err = store.Dot.Exec(
+		tx,
+		"v1-remove-chunks",
+		pad.ID,
+	)
+
+	if err != nil {
+		return
+	}
+
+	return store.record(tx, pad.ID, pad.Revision+1, pad.Content)
+}
+
+// Remove a pad, its history, its editors, its ops, its chunks, its attachments and its lockout from the database.
+func (store *SQLStore) Remove(id string) (err error) {
+	return store.transaction(func(tx *sql.Tx) (err error) {
+		for _, query := range []string{
+			"v1-remove-revisions",
+			"v1-remove-editors",
+			"v1-remove-ops",
+			"v1-remove-chunks",
+			"v1-remove-pad-upload-chunks",
+			"v1-remove-pad-uploads",
+			"v1-remove-attachments",
+			"v1-remove-removed-attachments",
+			"v1-remove-lockout",
+			"v1-remove-pad",
+		} {
+			_, err = store.Dot.Exec(
+				tx,
+				query,
+				id,
+			)
+
+			if err != nil {
+				return
+			}
+		}
+
+		return
+	})
+}
+
+// RemoveExpired removes every pad (and its history, editors, ops, chunks and attachments) which expired at or before a unix timestamp,
+// and the chunks of every upload which wasn't committed in time, returning the IDs of the pads removed.
+func (store *SQLStore) RemoveExpired(now int64) (removed []string, err error) {
+	err = store.transaction(func(tx *sql.Tx) (err error) {
+		// Find the pads first, as they are removed along with everything else.
+		rows, err := store.Dot.Query(
+			tx,
+			"v1-expired-pads",
+			now,
+		)
+
+		if err != nil {
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var id string
+			err = rows.Scan(&id)
+			if err != nil {
+				return
+			}
+
+			removed = append(removed, id)
+		}
+
+		err = rows.Err()
+		if err != nil {
+			return
+		}
+
+		// The rows must be closed before the transaction can run anything else.
+		rows.Close()
+
+		for _, query := range []string{
+			"v1-remove-expired-revisions",
+			"v1-remove-expired-editors",
+			"v1-remove-expired-ops",
+			"v1-remove-expired-chunks",
+			"v1-remove-expired-attachments",
+			"v1-remove-expired-lockouts",
+			"v1-remove-expired-upload-chunks",
+			"v1-remove-expired-pads",
+			"v1-remove-expired-uploads",
+			"v1-remove-expired-burnt",
+		} {
+			_, err = store.Dot.Exec(
+				tx,
+				query,
+				now,
+			)
+
+			if err != nil {
+				return
+			}
+		}
+
+		// Removed attachments only need remembering while their pads are at the revision they were removed at.
+		_, err = store.Dot.Exec(
+			tx,
+			"v1-remove-stale-removed-attachments",
+		)
+
+		return
+	})
+
+	return
+}
+
+// Usage counts the pads stored, and the bytes of their content (or chunks), attachments and uploads' staged chunks.
+func (store *SQLStore) Usage() (usage model.Usage, err error) {
+	row, err := store.Dot.QueryRow(
+		store.DB,
+		"v1-pad-usage",
+	)
+
+	if err != nil {
+		return
+	}
+
+	err = row.Scan(
+		&usage.Pads,
+		&usage.Bytes,
+	)
+
+	if err != nil {
+		return
+	}
+
+	row, err = store.Dot.QueryRow(
+		store.DB,
+		"v1-attachment-usage",
+	)
+
+	if err != nil {
+		return
+	}
+
+	var attachments int64
+	err = row.Scan(&attachments)
+	if err != nil {
+		return
+	}
+
+	row, err = store.Dot.QueryRow(
+		store.DB,
+		"v1-upload-usage",
+	)
+
+	if err != nil {
+		return
+	}
+
+	var staged int64
+	err = row.Scan(&staged)
+
+	usage.Bytes += attachments + staged
+	return
+}
+
+// Burn atomically reads and removes a pad, leaving a record that it was burnt.
+func (store *SQLStore) Burn(id string) (pad model.Pad, err error) {
+	err = store.transaction(func(tx *sql.Tx) (err error) {
+		row, err := store.Dot.QueryRow(
+			tx,
+			"v1-pad-from-id",
+			id,
+		)
+
+		if err != nil {
+			return
+		}
+
+		err = scan(&pad, row)
+		if err != nil {
+			return
+		}
+
+		// Only the reader which actually removes the pad gets to read it.
+		res, err := store.Dot.Exec(
+			tx,
+			"v1-burn-pad",
+			id,
+			pad.Revision,
+		)
+
+		if err != nil {
+			return
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return
+		}
+
+		if rows == 0 {
+			err = errBurnt
+			return
+		}
+
+		for _, query := range []string{
+			"v1-remove-revisions",
+			"v1-remove-editors",
+			"v1-remove-ops",
+			"v1-remove-chunks",
+			"v1-remove-pad-upload-chunks",
+			"v1-remove-pad-uploads",
+			"v1-remove-attachments",
+			"v1-remove-removed-attachments",
+			"v1-remove-lockout",
+		} {
+			_, err = store.Dot.Exec(
+				tx,
+				query,
+				id,
+			)
+
+			if err != nil {
+				return
+			}
+		}
+
+		_, err = store.Dot.Exec(
+			tx,
+			"v1-insert-burnt",
+			id,
+			time.Now().Add(burntRetention).Unix(),
+		)
+
+		return
+	})
+
+	return
+}
+
+// Burnt checks if a pad has been burnt.
+func (store *SQLStore) Burnt(id string) (burnt bool, err error) {
+	row, err := store.Dot.QueryRow(
+		store.DB,
+		"v1-burnt-from-id",
+		id,
+	)
+
+	if err != nil {
+		return
+	}
+
+	var count int
+	err = row.Scan(&count)
+	burnt = count != 0
+	return
+}
+
+// Editors lists the editors of a pad.
+func (store *SQLStore) Editors(id string) (editors []model.Editor, err error) {
+	rows, err := store.Dot.Query(
+		store.DB,
+		"v1-editors-from-id",
+		id,
+	)
+
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var editor model.Editor
+		err = rows.Scan(
+			&editor.Name,
+			&editor.Proof,
+		)
+
+		if err != nil {
+			return
+		}
+
+		editors = append(editors, editor)
+	}
+
+	err = rows.Err()
+	return
+}
+
+// Editor gets an editor of a pad by name.
+func (store *SQLStore) Editor(id, name string) (editor model.Editor, err error) {
+	row, err := store.Dot.QueryRow(
+		store.DB,
+		"v1-editor-from-name",
+		id,
+		name,
+	)
+
+	if err != nil {
+		return
+	}
+
+	err = row.Scan(
+		&editor.Name,
+		&editor.Proof,
+	)
+
+	return
+}
+
+// PutEditor adds an editor to a pad, or replaces the editor's proof.
+func (store *SQLStore) PutEditor(id string, editor model.Editor) (err error) {
+	_, err = store.Dot.Exec(
+		store.DB,
+		"v1-put-editor",
+		id,
+		editor.Name,
+		editor.Proof,
+	)
+
+	return
+}
+
+// RemoveEditor removes an editor from a pad.
+func (store *SQLStore) RemoveEditor(id, name string) (err error) {
+	_, err = store.Dot.Exec(
+		store.DB,
+		"v1-remove-editor",
+		id,
+		name,
+	)
+
+	return
+}
+
+// Lockout gets how many incorrect proofs a pad has been sent in a row, and when it is locked until.
+func (store *SQLStore) Lockout(id string) (lockout model.Lockout, err error) {
+	row, err := store.Dot.QueryRow(
+		store.DB,
+		"v1-lockout-from-id",
+		id,
+	)
+
+	if err != nil {
+		return
+	}
+
+	err = row.Scan(
+		&lockout.Failures,
+		&lockout.LockedUntil,
+	)
+
+	return
+}
+
+// AddFailure counts another incorrect proof sent to a pad.
+func (store *SQLStore) AddFailure(id string) (lockout model.Lockout, err error) {
+	err = store.transaction(func(tx *sql.Tx) (err error) {
+		_, err = store.Dot.Exec(
+			tx,
+			"v1-add-failure",
+			id,
+		)
+
+		if err != nil {
+			return
+		}
+
+		row, err := store.Dot.QueryRow(
+			tx,
+			"v1-lockout-from-id",
+			id,
+		)
+
+		if err != nil {
+			return
+		}
+
+		return row.Scan(
+			&lockout.Failures,
+			&lockout.LockedUntil,
+		)
+	})
+
+	return
+}
+
+// LockPad locks a pad until a unix timestamp.
+func (store *SQLStore) LockPad(id string, until int64) (err error) {
+	_, err = store.Dot.Exec(
+		store.DB,
+		"v1-lock-pad",
+		until,
+		id,
+	)
+
+	return
+}
+
+// RemoveLockout forgets a pad's incorrect proofs.
+func (store *SQLStore) RemoveLockout(id string) (err error) {
+	_, err = store.Dot.Exec(
+		store.DB,
+		"v1-remove-lockout",
+		id,
+	)
+
+	return
+}
+
+// Revisions lists the stored revisions of a pad, oldest first.
+func (store *SQLStore) Revisions(id string) (revisions []model.Revision, err error) {
+	rows, err := store.Dot.Query(
+		store.DB,
+		"v1-revisions-from-id",
+		id,
+	)
+
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var revision model.Revision
+		err = rows.Scan(
+			&revision.Revision,
+			&revision.Created,
+		)
+
+		if err != nil {
+			return
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	err = rows.Err()
+	return
+}
+
+// Revision gets a stored revision of a pad.
+func (store *SQLStore) Revision(id string, revision int64) (pad model.Pad, err error) {
+	row, err := store.Dot.QueryRow(
+		store.DB,
+		"v1-revision-from-id",
+		id,
+		revision,
+	)
+
+	if err != nil {
+		return
+	}
+
+	err = row.Scan(
+		&pad.ID,
+		&pad.Content,
+		&pad.Revision,
+	)
+
+	return
+}
+
+// Ops lists the ops of a pad after a sequence, oldest first.
+func (store *SQLStore) Ops(id string, since int64) (ops []model.Op, err error) {
+	rows, err := store.Dot.Query(
+		store.DB,
+		"v1-ops-from-id",
+		id,
+		since,
+	)
+
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var op model.Op
+		err = rows.Scan(
+			&op.Sequence,
+			&op.Content,
+			&op.Created,
+		)
+
+		if err != nil {
+			return
+		}
+
+		ops = append(ops, op)
+	}
+
+	err = rows.Err()
+	return
+}
+
+// OpSequence gets the sequence of the last op of a pad, whether it is still in the log or compacted.
+func (store *SQLStore) OpSequence(id string) (sequence int64, err error) {
+	return store.opSequence(store.DB, id)
+}
+
+// AppendOp adds an op to the end of a pad's log, if its sequence directly follows the last op.
+func (store *SQLStore) AppendOp(id string, op model.Op) (err error) {
+	return store.transaction(func(tx *sql.Tx) (err error) {
+		sequence, err := store.opSequence(tx, id)
+		if err != nil {
+			return
+		}
+
+		if op.Sequence != sequence+1 {
+			err = errStaleSequence
+			return
+		}
+
+		_, err = store.Dot.Exec(
+			tx,
+			"v1-insert-op",
+			id,
+			op.Sequence,
+			[]byte(op.Content),
+			op.Created,
+		)
+
+		return
+	})
+}
+
+// opSequence gets the sequence of the last op of a pad using a connection or transaction.
+func (store *SQLStore) opSequence(db dotsql.QueryRower, id string) (sequence int64, err error) {
+	row, err := store.Dot.QueryRow(
+		db,
+		"v1-op-sequence-from-id",
+		id,
+		id,
+	)
+
+	if err != nil {
+		return
+	}
+
+	err = row.Scan(&sequence)
+	return
+}
+
+// CreateUpload starts an upload of a pad's chunks, if its ID isn't taken and the pad doesn't have max open uploads.
+// The open uploads are counted by the insert itself, so concurrent uploads can't go over the limit.
+func (store *SQLStore) CreateUpload(upload model.Upload, max int) (err error) {
+	return store.transaction(func(tx *sql.Tx) (err error) {
+		_, err = store.upload(tx, upload.ID)
+		if err == nil {
+			err = errUploadExists
+			return
+		}
+
+		if err != sql.ErrNoRows {
+			return
+		}
+
+		res, err := store.Dot.Exec(
+			tx,
+			"v1-insert-upload",
+			upload.ID,
+			upload.Pad,
+			upload.Revision,
+			upload.Chunks,
+			upload.Size,
+			upload.ExpiresAt,
+			upload.Hashes,
+			max,
+			upload.Pad,
+			time.Now().Unix(),
+			max,
+		)
+
+		if err != nil {
+			return
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return
+		}
+
+		if rows != 1 {
+			err = errTooManyUploads
+		}
+
+		return
+	})
+}
+
+// Upload gets an upload given its ID.
+func (store *SQLStore) Upload(id string) (model.Upload, error) {
+	return store.upload(store.DB, id)
+}
+
+// upload gets an upload given its ID using a connection or transaction.
+func (store *SQLStore) upload(db dotsql.QueryRower, id string) (upload model.Upload, err error) {
+	row, err := store.Dot.QueryRow(
+		db,
+		"v1-upload-from-id",
+		id,
+	)
+
+	if err != nil {
+		return
+	}
+
+	err = row.Scan(
+		&upload.ID,
+		&upload.Pad,
+		&upload.Revision,
+		&upload.Chunks,
+		&upload.Size,
+		&upload.ExpiresAt,
+		&upload.Hashes,
+	)
+
+	return
+}
+
+// PutChunk stores a chunk of an upload, replacing it if it was already received,
+// unless the upload's chunks would add up to more than its size.
+// The chunks are added up by the replace itself, so concurrent chunks can't go over the size.
+func (store *SQLStore) PutChunk(upload string, index int, data []byte) (staged int64, err error) {
+	err = store.transaction(func(tx *sql.Tx) (err error) {
+		row, err := store.Dot.QueryRow(
+			tx,
+			"v1-upload-chunk-size",
+			upload,
+			index,
+		)
+
+		if err != nil {
+			return
+		}
+
+		var replaced int64
+		err = row.Scan(&replaced)
+		if err != nil && err != sql.ErrNoRows {
+			return
+		}
+
+		res, err := store.Dot.Exec(
+			tx,
+			"v1-put-upload-chunk",
+			upload,
+			index,
+			data,
+			hashHex(data),
+			upload,
+			upload,
+			index,
+			len(data),
+		)
+
+		if err != nil {
+			return
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return
+		}
+
+		if rows == 0 {
+			err = errPadTooLarge
+			return
+		}
+
+		staged = int64(len(data)) - replaced
+		return
+	})
+
+	return
+}
+
+// UploadChunks lists the chunks an upload has received, in order.
+func (store *SQLStore) UploadChunks(upload string) (chunks []model.Chunk, err error) {
+	rows, err := store.Dot.Query(
+		store.DB,
+		"v1-upload-chunks-from-id",
+		upload,
+	)
+
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var chunk model.Chunk
+		err = rows.Scan(
+			&chunk.Index,
+			&chunk.Size,
+			&chunk.Hash,
+		)
+
+		if err != nil {
+			return
+		}
+
+		chunks = append(chunks, chunk)
+	}
+
+	err = rows.Err()
+	return
+}
+
+// CommitUpload replaces a pad's content with an upload's chunks, if the pad is still at the upload's revision.
+func (store *SQLStore) CommitUpload(upload model.Upload) (err error) {
+	return store.transaction(func(tx *sql.Tx) (err error) {
+		res, err := store.Dot.Exec(
+			tx,
+			"v1-commit-upload-pad",
+			upload.Chunks,
+			upload.Size,
+			upload.Pad,
+			upload.Revision,
+		)
+
+		if err != nil {
+			return
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return
+		}
+
+		if rows == 0 {
+			err = errStaleRevision
+			return
+		}
+
+		_, err = store.Dot.Exec(
+			tx,
+			"v1-remove-chunks",
+			upload.Pad,
+		)
+
+		if err != nil {
+			return
+		}
+
+		_, err = store.Dot.Exec(
+			tx,
+			"v1-commit-upload-chunks",
+			upload.Pad,
+			upload.ID,
+		)
+
+		if err != nil {
+			return
+		}
+
+		for _, query := range []string{
+			"v1-remove-upload-chunks",
+			"v1-remove-upload",
+		} {
+			_, err = store.Dot.Exec(
+				tx,
+				query,
+				upload.ID,
+			)
+
+			if err != nil {
+				return
+			}
+		}
+
+		return
+	})
+}
+
+// Chunk gets a chunk of a pad's content.
+func (store *SQLStore) Chunk(id string, index int) (data []byte, err error) {
+	row, err := store.Dot.QueryRow(
+		store.DB,
+		"v1-chunk-from-id",
+		id,
+		index,
+	)
+
+	if err != nil {
+		return
+	}
+
+	err = row.Scan(&data)
+	return
+}
+
+// Attachments lists the attachments of a pad, oldest first.
+func (store *SQLStore) Attachments(id string) (attachments []model.Attachment, err error) {
+	rows, err := store.Dot.Query(
+		store.DB,
+		"v1-attachments-from-id",
+		id,
+	)
+
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var attachment model.Attachment
+		err = rows.Scan(
+			&attachment.ID,
+			&attachment.Size,
+			&attachment.Created,
+		)
+
+		if err != nil {
+			return
+		}
+
+		attachments = append(attachments, attachment)
+	}
+
+	err = rows.Err()
+	return
+}
+
+// Attachment gets an attachment of a pad and its data.
+func (store *SQLStore) Attachment(id, attachmentID string) (model.Attachment, []byte, error) {
+	return store.attachment(store.DB, id, attachmentID)
+}
+
+// attachment gets an attachment of a pad and its data using a connection or transaction.
+func (store *SQLStore) attachment(db dotsql.QueryRower, id, attachmentID string) (attachment model.Attachment, data []byte, err error) {
+	row, err := store.Dot.QueryRow(
+		db,
+		"v1-attachment-from-id",
+		id,
+		attachmentID,
+	)
+
+	if err != nil {
+		return
+	}
+
+	err = row.Scan(
+		&attachment.ID,
+		&attachment.Size,
+		&attachment.Created,
+		&data,
+	)
+
+	return
+}
+
+// PutAttachment adds an attachment to a pad, if it doesn't have (or hasn't had) the attachment or max attachments.
+// The attachments are counted by the insert itself, so concurrent attachments can't go over the limit.
+func (store *SQLStore) PutAttachment(id string, attachment model.Attachment, data []byte, max int) (err error) {
+	return store.transaction(func(tx *sql.Tx) (err error) {
+		_, _, err = store.attachment(tx, id, attachment.ID)
+		if err == nil {
+			err = errAttachmentExists
+			return
+		}
+
+		if err != sql.ErrNoRows {
+			return
+		}
+
+		row, err := store.Dot.QueryRow(
+			tx,
+			"v1-removed-attachment",
+			id,
+			attachment.ID,
+		)
+
+		if err != nil {
+			return
+		}
+
+		var revision int64
+		err = row.Scan(&revision)
+		if err == nil {
+			err = errAttachmentExists
+			return
+		}
+
+		if err != sql.ErrNoRows {
+			return
+		}
+
+		res, err := store.Dot.Exec(
+			tx,
+			"v1-insert-attachment",
+			id,
+			attachment.ID,
+			attachment.Size,
+			attachment.Created,
+			data,
+			id,
+			max,
+		)
+
+		if err != nil {
+			return
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return
+		}
+
+		if rows != 1 {
+			err = errTooManyAttachments
+		}
+
+		return
+	})
+}
+
+// RemoveAttachment removes an attachment from a pad, remembering its ID at the pad's revision.
+func (store *SQLStore) RemoveAttachment(id, attachmentID string) (err error) {
+	return store.transaction(func(tx *sql.Tx) (err error) {
+		_, err = store.Dot.Exec(
+			tx,
+			"v1-remove-attachment",
+			id,
+			attachmentID,
+		)
+
+		if err != nil {
+			return
+		}
+
+		_, err = store.Dot.Exec(
+			tx,
+			"v1-insert-removed-attachment",
+			attachmentID,
+			id,
+		)
+
+		return
+	})
+}
+
+// execOne runs a named query in a transaction which must change exactly one row, otherwise errStaleRevision is returned.
+func (store *SQLStore) execOne(tx *sql.Tx, query string, args ...interface{}) (err error) {
+	res, err := store.Dot.Exec(
+		tx,
+		query,
+		args...,
 	)
 
+	if err != nil {
+		return
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+
+	if rows != 1 {
+		err = errStaleRevision
+	}
+
 	return
 }
 
-// Remove a pad from the database.
-func Remove(id string) (err error) {
-	_, err = db.Dot.Exec(
-		db.SQL,
-		"v1-remove-pad",
+// count checks a named count query in a transaction gives the expected count, otherwise errStaleRevision is returned.
+func (store *SQLStore) count(tx *sql.Tx, query, id string, expected int) (err error) {
+	row, err := store.Dot.QueryRow(
+		tx,
+		query,
 		id,
 	)
 
+	if err != nil {
+		return
+	}
+
+	var count int
+	err = row.Scan(&count)
+	if err != nil {
+		return
+	}
+
+	if count != expected {
+		err = errStaleRevision
+	}
+
+	return
+}
+
+// record stores a revision of a pad, removing any revisions older than the history allows.
+func (store *SQLStore) record(tx *sql.Tx, id string, revision int64, content model.Ciphertext) (err error) {
+	if store.History <= 0 {
+		return
+	}
+
+	_, err = store.Dot.Exec(
+		tx,
+		"v1-insert-revision",
+		id,
+		revision,
+		[]byte(content),
+		time.Now().Unix(),
+	)
+
+	if err != nil {
+		return
+	}
+
+	// Revisions are sequential, so everything at or below this revision is too old.
+	_, err = store.Dot.Exec(
+		tx,
+		"v1-prune-revisions",
+		id,
+		revision-int64(store.History),
+	)
+
+	return
+}
+
+// transaction runs a function in a transaction, committing if it succeeds and rolling back if it doesn't.
+func (store *SQLStore) transaction(fn func(tx *sql.Tx) error) (err error) {
+	tx, err := store.DB.Begin()
+	if err != nil {
+		return
+	}
+
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return
+	}
+
+	err = tx.Commit()
 	return
 }
 
@@ -67,5 +1272,13 @@ func scan(pad *model.Pad, row *sql.Row) error {
 		&pad.ID,
 		&pad.Content,
 		&pad.Proof,
+		&pad.Revision,
+		&pad.ExpiresAt,
+		&pad.BurnAfterReading,
+		&pad.PublicKey,
+		&pad.ReadToken,
+		&pad.Compacted,
+		&pad.Chunks,
+		&pad.Size,
 	)
 }
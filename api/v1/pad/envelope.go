@@ -0,0 +1,36 @@
+package pad
+
+import (
+	"errors"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+)
+
+var (
+	errNoEnvelope = errors.New("content must be in an envelope")
+)
+
+// checkEnvelope checks the structure of the envelope content is in, without decrypting it.
+// Content from before envelopes is only allowed if the config doesn't require them, and empty content always is.
+func checkEnvelope(content model.Ciphertext) error {
+	_, err := model.EnvelopeOf(content)
+	if err == model.ErrNoEnvelope {
+		if cfg.RequireEnvelope && content != "" {
+			return errNoEnvelope
+		}
+
+		return nil
+	}
+
+	return err
+}
+
+// envelopeVersion gets the version of the envelope content is in, or 0 if it isn't in one.
+func envelopeVersion(content model.Ciphertext) int {
+	env, err := model.EnvelopeOf(content)
+	if err != nil {
+		return 0
+	}
+
+	return int(env.Version)
+}
@@ -0,0 +1,178 @@
+package pad
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+)
+
+var (
+	errNoStreaming = errors.New("streaming is not supported by this connection")
+)
+
+// Events streams a pad's events as server-sent events, for clients which can't use WebSockets.
+// Event IDs are revisions, so a reconnecting client's Last-Event-ID resumes from the revision it last received.
+// As browsers only send Last-Event-ID when reconnecting, the first connection can resume from a since query parameter.
+func Events(w http.ResponseWriter, r *http.Request) {
+	pad, err := subscribable(r)
+	if err != nil {
+		helper.ThrowErr(err, subscribeErrStatus(err), w)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		helper.ThrowErr(errNoStreaming, http.StatusInternalServerError, w)
+		return
+	}
+
+	// Subscribe before replaying, so no updates are missed in between.
+	sub := subscribe(pad.ID)
+	defer unsubscribe(pad.ID, sub)
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("since")
+	}
+
+	last, _ := strconv.ParseInt(lastEventID, 10, 64)
+
+	missed, err := missedEvents(pad, last)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// The server's write timeout would end the stream, so each write gets its own deadline instead.
+	controller := http.NewResponseController(w)
+	controller.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	for _, event := range missed {
+		err = writeEvent(w, event)
+		if err != nil {
+			return
+		}
+
+		if event.Type == EventUpdated {
+			last = event.Revision
+		}
+	}
+
+	flusher.Flush()
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.events:
+			if !ok {
+				// The pad was deleted or we fell behind, either way there is nothing more to send.
+				return
+			}
+
+			// Skip updates which were already replayed.
+			if event.Type == EventUpdated && event.Revision <= last {
+				continue
+			}
+
+			controller.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+			err = writeEvent(w, event)
+			if err != nil {
+				return
+			}
+
+			flusher.Flush()
+
+			// Only updates have revisions, other events (such as ops) don't move the stream on.
+			if event.Type == EventUpdated {
+				last = event.Revision
+			}
+		case <-ping.C:
+			// Comments keep the connection alive through proxies without being seen by clients.
+			controller.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+			_, err = fmt.Fprint(w, ": ping\n\n")
+			if err != nil {
+				return
+			}
+
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// missedEvents gets the updates to a pad after a revision, from its history.
+// If the history doesn't reach back far enough, the client still gets the current content.
+func missedEvents(pad model.Pad, last int64) (events []model.Event, err error) {
+	if last <= 0 || last >= pad.Revision {
+		return
+	}
+
+	revisions, err := Store.Revisions(pad.ID)
+	if err != nil {
+		return
+	}
+
+	for _, revision := range revisions {
+		if revision.Revision <= last || revision.Revision > pad.Revision {
+			continue
+		}
+
+		var stored model.Pad
+		stored, err = Store.Revision(pad.ID, revision.Revision)
+		if err != nil {
+			return
+		}
+
+		events = append(events, model.Event{
+			Type:     EventUpdated,
+			ID:       pad.ID,
+			Revision: stored.Revision,
+			Content:  stored.Content,
+		})
+	}
+
+	if len(events) == 0 || events[len(events)-1].Revision != pad.Revision {
+		events = append(events, model.Event{
+			Type:     EventUpdated,
+			ID:       pad.ID,
+			Revision: pad.Revision,
+			Content:  pad.Content,
+			Chunks:   pad.Chunks,
+		})
+	}
+
+	return
+}
+
+// writeEvent writes an event in the server-sent events format.
+func writeEvent(w http.ResponseWriter, event model.Event) (err error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	if event.Type == EventUpdated {
+		_, err = fmt.Fprintf(w, "id: %v\n", event.Revision)
+		if err != nil {
+			return
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "event: %v\ndata: %s\n\n", event.Type, data)
+	return
+}
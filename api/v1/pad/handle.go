@@ -2,13 +2,16 @@ package pad
 
 import (
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
 	"github.com/VolticFroogo/cryptopad-server/helper"
+	"github.com/VolticFroogo/cryptopad-server/ratelimit"
 	"github.com/gorilla/mux"
 )
 
@@ -19,7 +22,10 @@ var (
 	errInvalidProofLen    = fmt.Errorf("proofs must be 0 or %v in length", model.ProofLen)
 	errInvalidNewProofLen = fmt.Errorf("new proofs must be 0 or %v in length", model.ProofLen)
 	errNoProof            = errors.New("proof can not be empty")
-	errNoNewProof         = errors.New("new proof can not be empty")
+	errNoNewProof         = errors.New("new proof can not be empty without a new public key")
+	errRevisionMismatch   = errors.New("pad is not at the revision in if-match")
+	errInvalidExpiry      = errors.New("expiry must be in the future")
+	errBurnt              = errors.New("pad has already been read and burnt")
 )
 
 // Get a pad.
@@ -36,32 +42,113 @@ func Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the pad (if it exists) from the database with a matching ID.
-	pad, err := FromID(data.ID)
+	// Get the pad (if it exists and can be read) from the database with a matching ID.
+	pad, err := read(r, data.ID, true)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			helper.ThrowErr(err, http.StatusNotFound, w)
-			return
-		}
-
-		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		helper.ThrowErr(err, readErrStatus(err), w)
 		return
 	}
 
 	pad.Proof = ""
 	pad.NewProof = ""
+	pad.ReadToken = ""
+	pad.Envelope = envelopeVersion(pad.Content)
+
+	// Return the pad to the client, tagged with its revision.
+	w.Header().Set("ETag", etag(pad.Revision))
+
+	// Clients can ask for only the content, as raw bytes.
+	if helper.Accepted(r, helper.FormatJSON, helper.FormatCBOR, helper.FormatMsgpack, helper.FormatOctetStream) == helper.FormatOctetStream {
+		if pad.Chunks > 0 {
+			w.Header().Set("X-Chunks", strconv.Itoa(pad.Chunks))
+		}
+
+		if pad.Envelope != 0 {
+			w.Header().Set("X-Envelope", strconv.Itoa(pad.Envelope))
+		}
+
+		w.Header().Set("Content-Type", helper.FormatOctetStream)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(pad.Content))
+		return
+	}
+
+	helper.Response(pad, http.StatusOK, w)
+}
+
+// Head checks if a pad exists and can be read, without downloading (or burning) it.
+func Head(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(id) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	pad, err := read(r, id, false)
+	if err != nil {
+		w.WriteHeader(readErrStatus(err))
+		return
+	}
+
+	w.Header().Set("ETag", etag(pad.Revision))
+	w.WriteHeader(http.StatusOK)
+}
+
+// read gets a pad which a request may read, burning it if it is burn after reading and burn is set.
+func read(r *http.Request, id string, burn bool) (pad model.Pad, err error) {
+	pad, err = FromID(id)
+
+	// Check the reader has the read token, if the pad needs one.
+	if err == nil {
+		err = authoriseRead(pad, r)
+	}
 
-	// Return the pad to the client.
-	helper.JSONResponse(pad, http.StatusOK, w)
+	// Burn after reading pads are read and removed at once, so only one reader gets them.
+	if err == nil && burn && pad.BurnAfterReading {
+		pad, err = Store.Burn(id)
+		if err == nil {
+			publishDelete(id)
+		}
+	}
+
+	if err == sql.ErrNoRows {
+		// Tell readers of burnt pads that they are gone, rather than that they never existed.
+		var burnt bool
+		burnt, err = Store.Burnt(id)
+		if err == nil {
+			err = sql.ErrNoRows
+			if burnt {
+				err = errBurnt
+			}
+		}
+	}
+
+	return
+}
+
+// readErrStatus gets the status code for an error from reading a pad.
+func readErrStatus(err error) int {
+	switch err {
+	case sql.ErrNoRows:
+		return http.StatusNotFound
+	case errBurnt:
+		return http.StatusGone
+	case errIncorrectReadToken:
+		return http.StatusForbidden
+	}
+
+	return http.StatusInternalServerError
 }
 
 // Put (overwrite / create) a pad.
 func Put(w http.ResponseWriter, r *http.Request) {
-	// Get data from the JSON request.
+	// Get data from the request, in whichever format it was sent.
 	var data model.Pad
-	err := json.NewDecoder(r.Body).Decode(&data)
+	err := decode(w, r, &data)
 	if err != nil {
-		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		helper.ThrowErr(err, helper.DecodeErrStatus(err), w)
 		return
 	}
 
@@ -71,6 +158,11 @@ func Put(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Limit requests to the pad, as its ID isn't in the URL for the middleware to limit them by.
+	if !ratelimit.Pad(w, r, data.ID) {
+		return
+	}
+
 	// Check if the proof is a valid length.
 	pLen := len(data.Proof)
 	if pLen != 0 && pLen != model.ProofLen {
@@ -86,11 +178,51 @@ func Put(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if the content is a valid length.
-	if !model.ContentLen.Check(data.Content) {
+	if !model.ContentLen.Check(string(data.Content)) {
 		helper.ThrowErr(errInvalidContentLen, http.StatusBadRequest, w)
 		return
 	}
 
+	// Check the content is in a well formed envelope, so broken clients can't store content nobody can decrypt.
+	err = checkEnvelope(data.Content)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
+		return
+	}
+
+	// Check if the new read token is a valid length.
+	nrtLen := len(data.NewReadToken)
+	if nrtLen != 0 && nrtLen != model.ProofLen {
+		helper.ThrowErr(errInvalidReadTokenLen, http.StatusBadRequest, w)
+		return
+	}
+
+	// Check if the new public key is valid.
+	if data.NewPublicKey != "" {
+		_, err = decodePublicKey(data.NewPublicKey)
+		if err != nil {
+			helper.ThrowErr(err, http.StatusBadRequest, w)
+			return
+		}
+	}
+
+	// Check the ops being compacted are a valid sequence.
+	if data.Compacted < 0 {
+		helper.ThrowErr(errInvalidSequence, http.StatusBadRequest, w)
+		return
+	}
+
+	// Convert a TTL into an expiry, and check the expiry hasn't already passed.
+	now := time.Now().Unix()
+	if data.TTL != 0 {
+		data.ExpiresAt = now + data.TTL
+	}
+
+	if data.ExpiresAt != 0 && data.ExpiresAt <= now {
+		helper.ThrowErr(errInvalidExpiry, http.StatusBadRequest, w)
+		return
+	}
+
 	// Get the pad (if it exists) from the database with a matching ID.
 	pad, err := FromID(data.ID)
 	if err != nil && err != sql.ErrNoRows {
@@ -98,53 +230,148 @@ func Put(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// If the client only wants to update a specific revision, check it is still current.
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch != "" {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(errRevisionMismatch, http.StatusPreconditionFailed, w)
+			return
+		}
+
+		if !matchesRevision(ifMatch, pad.Revision) {
+			w.Header().Set("ETag", etag(pad.Revision))
+			helper.ThrowErr(errRevisionMismatch, http.StatusPreconditionFailed, w)
+			return
+		}
+	}
+
 	if err == sql.ErrNoRows { // If the pad doesn't exist:
 		// Check again that the new proof is the right length.
-		// This is necessary as 0 could pass earlier, but shouldn't now.
-		if npLen != model.ProofLen {
+		// This is necessary as 0 could pass earlier, but shouldn't now unless the pad is owned by a public key.
+		if npLen != model.ProofLen && data.NewPublicKey == "" {
 			helper.ThrowErr(errNoNewProof, http.StatusBadRequest, w)
 			return
 		}
 
+		// Check the server has room for the pad, and the client hasn't created too many.
+		// This counts the pad against the client's quota, which is given back if it isn't created.
+		err = checkQuota(r)
+		if err != nil {
+			helper.ThrowErr(err, quotaErrStatus(err), w)
+			return
+		}
+
+		// Check the client did the work to create a pad, if the server requires it.
+		err = checkWork(data)
+		if err != nil {
+			releaseQuota(r)
+			helper.ThrowErr(err, workErrStatus(err), w)
+			return
+		}
+
+		// Check nobody else has reserved the ID.
+		err = claim(data.ID, data.Reservation)
+		if err != nil {
+			releaseQuota(r)
+			helper.ThrowErr(err, http.StatusConflict, w)
+			return
+		}
+
 		// Just insert a new pad into the database.
 		err = Insert(data)
 
 		if err != nil {
+			releaseQuota(r)
 			helper.ThrowErr(err, http.StatusInternalServerError, w)
 			return
 		}
 
+		recordCreation()
+
+		w.Header().Set("ETag", etag(1))
 		w.WriteHeader(http.StatusCreated)
 	} else { // If the pad does exist:
 		// Update the pad if the proof matches.
 		err = updateIfTrusted(pad, data)
 
 		if err != nil {
-			if err == errIncorrectProof {
-				helper.ThrowErr(err, http.StatusForbidden, w)
+			if isAuthErr(err) {
+				throwAuthErr(err, w)
 				return
 			}
 
-			helper.ThrowErr(err, http.StatusInternalServerError, w)
+			// Another update was applied between reading and updating the pad.
+			if err == errStaleRevision {
+				status := http.StatusConflict
+				if ifMatch != "" {
+					status = http.StatusPreconditionFailed
+				}
+
+				helper.ThrowErr(err, status, w)
+				return
+			}
+
+			helper.ThrowErr(err, compactErrStatus(err), w)
 			return
 		}
 
+		w.Header().Set("ETag", etag(pad.Revision+1))
 		w.WriteHeader(http.StatusOK)
 	}
 }
 
 func updateIfTrusted(pad, data model.Pad) (err error) {
-	// Check if the proofs match.
-	if pad.Proof != data.Proof {
-		err = errIncorrectProof
+	// Check if the proofs match, the challenge was answered, or the update was signed.
+	owner, err := authorise(pad, data, updateMessage(data, pad.Revision))
+	if err != nil {
+		return
+	}
+
+	// Editors can only change the content.
+	if !owner && ownerOnly(data) {
+		err = errNotOwner
 		return
 	}
 
 	// If the new proof is empty, set it to the current proof.
+	// Rehashing the owner's proof upgrades legacy proofs, otherwise the stored hash is kept.
 	if data.NewProof == "" {
-		data.NewProof = data.Proof
+		if owner && data.Proof != "" {
+			data.NewProof = data.Proof
+		} else {
+			data.Proof = pad.Proof
+		}
 	}
 
+	// If the new public key is empty, keep the current one.
+	data.PublicKey = pad.PublicKey
+	if data.NewPublicKey != "" {
+		data.PublicKey = data.NewPublicKey
+	}
+
+	// If the new read token is empty, keep the current one.
+	data.ReadToken = pad.ReadToken
+	if data.NewReadToken != "" {
+		data.ReadToken = hashReadToken(data.NewReadToken)
+	}
+
+	// If no new expiry was given, keep the current one.
+	if data.ExpiresAt == 0 {
+		data.ExpiresAt = pad.ExpiresAt
+	}
+
+	// Once a pad is burn after reading, it stays that way.
+	data.BurnAfterReading = data.BurnAfterReading || pad.BurnAfterReading
+
+	// Compacting ops into the content removes them from the log.
+	err = compact(pad, &data)
+	if err != nil {
+		return
+	}
+
+	// Only update the revision we checked the proof against.
+	data.Revision = pad.Revision
+
 	// Update the pad in the database.
 	err = Update(data)
 	return
@@ -152,11 +379,11 @@ func updateIfTrusted(pad, data model.Pad) (err error) {
 
 // Delete a pad.
 func Delete(w http.ResponseWriter, r *http.Request) {
-	// Get data from the JSON request.
+	// Get data from the request, in whichever format it was sent.
 	var data model.Pad
-	err := json.NewDecoder(r.Body).Decode(&data)
+	err := decode(w, r, &data)
 	if err != nil {
-		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		helper.ThrowErr(err, helper.DecodeErrStatus(err), w)
 		return
 	}
 
@@ -166,10 +393,15 @@ func Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if the proof is a valid length.
-	pLen := len(data.Proof)
-	if pLen != model.ProofLen {
-		helper.ThrowErr(errNoProof, http.StatusBadRequest, w)
+	// Limit requests to the pad, as its ID isn't in the URL for the middleware to limit them by.
+	if !ratelimit.Pad(w, r, data.ID) {
+		return
+	}
+
+	// Check if the proof is a valid length, or there is a challenge response instead.
+	err = checkAuthLen(data)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
 		return
 	}
 
@@ -185,8 +417,13 @@ func Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if data.Proof != pad.Proof {
-		helper.ThrowErr(errIncorrectProof, http.StatusForbidden, w)
+	owner, err := authorise(pad, data, deleteMessage(pad.ID, pad.Revision))
+	if err == nil && !owner {
+		err = errNotOwner
+	}
+
+	if err != nil {
+		throwAuthErr(err, w)
 		return
 	}
 
@@ -198,3 +435,25 @@ func Delete(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// etag formats a revision as an entity tag.
+func etag(revision int64) string {
+	return `"` + strconv.FormatInt(revision, 10) + `"`
+}
+
+// matchesRevision checks if an If-Match header matches a revision.
+func matchesRevision(ifMatch string, revision int64) bool {
+	if strings.TrimSpace(ifMatch) == "*" {
+		return true
+	}
+
+	// The header can be a list of entity tags, any of which may match.
+	tag := etag(revision)
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		if strings.TrimSpace(candidate) == tag {
+			return true
+		}
+	}
+
+	return false
+}
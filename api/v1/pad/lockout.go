@@ -0,0 +1,135 @@
+package pad
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+)
+
+var (
+	errLockedOut = errors.New("pad is locked after too many incorrect proofs")
+)
+
+// lockoutError is an error from authorising a request to a pad, along with the pad's lockout after it.
+type lockoutError struct {
+	err     error
+	lockout model.Lockout
+}
+
+// Error describes the error from authorising the request.
+func (err *lockoutError) Error() string {
+	return err.err.Error()
+}
+
+// Unwrap gets the error from authorising the request.
+func (err *lockoutError) Unwrap() error {
+	return err.err
+}
+
+// lockoutResponse is the type used for error responses which count towards, or are because of, a lockout.
+type lockoutResponse struct {
+	helper.ErrorResponse
+	Lockout model.Lockout
+}
+
+// checkLockout gets a pad's lockout, and returns a *lockoutError if the pad is locked.
+func checkLockout(id string) (lockout model.Lockout, err error) {
+	if cfg.LockoutThreshold <= 0 {
+		return
+	}
+
+	lockout, err = Store.Lockout(id)
+	if err == sql.ErrNoRows {
+		return model.Lockout{}, nil
+	}
+
+	if err != nil {
+		return
+	}
+
+	if lockout.LockedUntil > time.Now().Unix() {
+		err = &lockoutError{errLockedOut, lockout}
+	}
+
+	return
+}
+
+// isIncorrectProof checks if an error is from a request sending an incorrect proof, which could be a guess.
+// Expired challenges and pads without public keys aren't guesses, so they don't count.
+func isIncorrectProof(err error) bool {
+	switch err {
+	case errIncorrectProof, errIncorrectEditorProof, errIncorrectResponse, errIncorrectSignature:
+		return true
+	}
+
+	return false
+}
+
+// fail counts an incorrect proof sent to a pad, and locks the pad once there have been too many in a row.
+// The error from the proof is returned as a *lockoutError, along with the pad's lockout.
+func fail(id string, proofErr error) (err error) {
+	if cfg.LockoutThreshold <= 0 {
+		return proofErr
+	}
+
+	lockout, err := Store.AddFailure(id)
+	if err != nil {
+		return
+	}
+
+	if lockout.Failures >= cfg.LockoutThreshold {
+		lockout.LockedUntil = time.Now().Unix() + lockoutDuration(lockout.Failures-cfg.LockoutThreshold)
+
+		err = Store.LockPad(id, lockout.LockedUntil)
+		if err != nil {
+			return
+		}
+	}
+
+	return &lockoutError{proofErr, lockout}
+}
+
+// lockoutDuration gets how long, in seconds, a pad is locked for after a number of incorrect proofs past the threshold.
+// Locked pads can't be sent proofs, so each one after the threshold was sent after the last lockout ended, and doubles it.
+func lockoutDuration(past int) int64 {
+	// Without a maximum, the duration stops doubling well before adding it to the time could overflow.
+	duration := cfg.LockoutDuration
+	for i := 0; i < past && (cfg.MaxLockoutDuration <= 0 || duration < cfg.MaxLockoutDuration) && duration <= math.MaxInt64/4; i++ {
+		duration *= 2
+	}
+
+	if cfg.MaxLockoutDuration > 0 && duration > cfg.MaxLockoutDuration {
+		duration = cfg.MaxLockoutDuration
+	}
+
+	return duration
+}
+
+// throwAuthErr sends an error from authorising a request, along with the pad's lockout if it has one.
+// Requests to locked pads are sent 429 Too Many Requests, with how many seconds until the pad is unlocked.
+func throwAuthErr(err error, w http.ResponseWriter) {
+	var lockoutErr *lockoutError
+	if !errors.As(err, &lockoutErr) {
+		helper.ThrowErr(err, http.StatusForbidden, w)
+		return
+	}
+
+	status := http.StatusForbidden
+	if lockoutErr.err == errLockedOut {
+		status = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", strconv.FormatInt(lockoutErr.lockout.LockedUntil-time.Now().Unix(), 10))
+	}
+
+	helper.Response(lockoutResponse{
+		ErrorResponse: helper.ErrorResponse{
+			Error: err.Error(),
+		},
+		Lockout: lockoutErr.lockout,
+	}, status, w)
+}
@@ -0,0 +1,37 @@
+package pad
+
+import (
+	"math"
+	"testing"
+)
+
+// TestLockoutDuration tests lockouts double with each incorrect proof past the threshold, up to the maximum if there is one.
+func TestLockoutDuration(t *testing.T) {
+	defer func(previous Config) {
+		cfg = previous
+	}(cfg)
+
+	for _, test := range []struct {
+		duration, max int64
+		past          int
+		expected      int64
+	}{
+		{60, 3600, 0, 60},
+		{60, 3600, 1, 120},
+		{60, 3600, 3, 480},
+		{60, 3600, 10, 3600},
+		{60, 0, 0, 60},
+		{60, 0, 1, 120},
+		{60, 0, 10, 61440},
+		{60, 0, 100, 60 << 56},
+		{math.MaxInt64 / 4, 0, 3, math.MaxInt64 / 4 * 2},
+	} {
+		cfg.LockoutDuration = test.duration
+		cfg.MaxLockoutDuration = test.max
+
+		duration := lockoutDuration(test.past)
+		if duration != test.expected {
+			t.Errorf("lockout duration: %v past the threshold of %+v was %v rather than %v", test.past, test, duration, test.expected)
+		}
+	}
+}
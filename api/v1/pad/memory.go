@@ -0,0 +1,776 @@
+package pad
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+)
+
+var (
+	errPadExists = errors.New("a pad with that id already exists")
+)
+
+// MemoryStore is a PadStore which keeps pads in memory.
+type MemoryStore struct {
+	mutex     sync.RWMutex
+	pads      map[string]model.Pad
+	revisions map[string][]revision
+	burnt     map[string]int64
+	editors   map[string]map[string]model.Editor
+	ops       map[string][]model.Op
+	chunks    map[string][][]byte
+	lockouts  map[string]model.Lockout
+
+	// attachments are the attachments of each pad, oldest first.
+	// removedAttachments are the IDs of each pad's removed attachments, with the revision they were removed at.
+	attachments        map[string][]attachment
+	removedAttachments map[string]map[string]int64
+
+	// uploads are staged until they are committed, with their chunks by index.
+	uploads      map[string]model.Upload
+	uploadChunks map[string]map[int][]byte
+
+	// History is how many revisions of each pad to keep.
+	History int
+}
+
+// attachment is a stored attachment of a pad.
+type attachment struct {
+	model.Attachment
+	Data []byte
+}
+
+// revision is a stored revision of a pad.
+type revision struct {
+	model.Revision
+	Content model.Ciphertext
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore(history int) *MemoryStore {
+	return &MemoryStore{
+		pads:      make(map[string]model.Pad),
+		revisions: make(map[string][]revision),
+		burnt:     make(map[string]int64),
+		editors:   make(map[string]map[string]model.Editor),
+		ops:       make(map[string][]model.Op),
+		chunks:    make(map[string][][]byte),
+		lockouts:  make(map[string]model.Lockout),
+		History:   history,
+
+		attachments:        make(map[string][]attachment),
+		removedAttachments: make(map[string]map[string]int64),
+
+		uploads:      make(map[string]model.Upload),
+		uploadChunks: make(map[string]map[int][]byte),
+	}
+}
+
+// FromID gets a pad from memory given an ID.
+func (store *MemoryStore) FromID(id string) (pad model.Pad, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	pad, ok := store.pads[id]
+	if !ok {
+		err = sql.ErrNoRows
+	}
+
+	return
+}
+
+// Insert a pad into memory.
+func (store *MemoryStore) Insert(pad model.Pad) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, ok := store.pads[pad.ID]; ok {
+		err = errPadExists
+		return
+	}
+
+	pad.Revision = 1
+	store.pads[pad.ID] = stored(pad)
+	delete(store.burnt, pad.ID)
+	store.record(pad)
+	return
+}
+
+// Update a pad in memory if it is still at the pad's revision.
+func (store *MemoryStore) Update(pad model.Pad) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	current, ok := store.pads[pad.ID]
+	if !ok || current.Revision != pad.Revision {
+		err = errStaleRevision
+		return
+	}
+
+	pad.Revision++
+	store.pads[pad.ID] = stored(pad)
+	store.record(pad)
+
+	// The content replaces any chunks the pad had.
+	delete(store.chunks, pad.ID)
+
+	// Ops which have been compacted into the content are no longer needed.
+	ops := store.ops[pad.ID]
+	for len(ops) != 0 && ops[0].Sequence <= pad.Compacted {
+		ops = ops[1:]
+	}
+
+	store.ops[pad.ID] = ops
+	return
+}
+
+// Rotate replaces a pad along with its re-encrypted history and attachments, and removes its editors, ops and uploads.
+// If the pad has a revision or attachment which isn't being replaced, or ops which weren't compacted, nothing is changed.
+func (store *MemoryStore) Rotate(pad model.Pad, revisions map[int64]model.Ciphertext, attachments map[string][]byte) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	current, ok := store.pads[pad.ID]
+	if !ok || current.Revision != pad.Revision {
+		err = errStaleRevision
+		return
+	}
+
+	sequence, err := store.opSequence(pad.ID)
+	if err != nil {
+		return
+	}
+
+	if sequence != pad.Compacted {
+		err = errStaleSequence
+		return
+	}
+
+	// Check everything is being replaced before changing anything.
+	if len(store.revisions[pad.ID]) != len(revisions) || len(store.attachments[pad.ID]) != len(attachments) {
+		err = errStaleRevision
+		return
+	}
+
+	rotatedRevisions := make([]revision, len(revisions))
+	for i, kept := range store.revisions[pad.ID] {
+		content, ok := revisions[kept.Revision.Revision]
+		if !ok {
+			err = errStaleRevision
+			return
+		}
+
+		kept.Content = content
+		rotatedRevisions[i] = kept
+	}
+
+	rotatedAttachments := make([]attachment, len(attachments))
+	for i, kept := range store.attachments[pad.ID] {
+		data, ok := attachments[kept.ID]
+		if !ok {
+			err = errStaleRevision
+			return
+		}
+
+		kept.Size = int64(len(data))
+		kept.Data = data
+		rotatedAttachments[i] = kept
+	}
+
+	store.revisions[pad.ID] = rotatedRevisions
+	store.attachments[pad.ID] = rotatedAttachments
+
+	pad.Revision++
+	store.pads[pad.ID] = stored(pad)
+	store.record(pad)
+
+	delete(store.chunks, pad.ID)
+	delete(store.editors, pad.ID)
+	delete(store.ops, pad.ID)
+
+	for upload, other := range store.uploads {
+		if other.Pad == pad.ID {
+			delete(store.uploads, upload)
+			delete(store.uploadChunks, upload)
+		}
+	}
+
+	return
+}
+
+// Remove a pad, its history, its editors, its ops, its chunks and its attachments from memory.
+func (store *MemoryStore) Remove(id string) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.remove(id)
+	return
+}
+
+// RemoveExpired removes every pad (and its history, editors, ops, chunks and attachments) which expired at or before a unix timestamp,
+// and the chunks of every upload which wasn't committed in time, returning the IDs of the pads removed.
+func (store *MemoryStore) RemoveExpired(now int64) (removed []string, err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for id, pad := range store.pads {
+		if pad.ExpiresAt != 0 && pad.ExpiresAt <= now {
+			store.remove(id)
+			removed = append(removed, id)
+		}
+	}
+
+	// Expired uploads are kept, without their chunks, while the pad is still at their revision,
+	// so the signatures which started them can't start them again.
+	for id, upload := range store.uploads {
+		if upload.ExpiresAt <= now {
+			delete(store.uploadChunks, id)
+
+			if pad, ok := store.pads[upload.Pad]; !ok || pad.Revision != upload.Revision {
+				delete(store.uploads, id)
+			}
+		}
+	}
+
+	for id, expiresAt := range store.burnt {
+		if expiresAt <= now {
+			delete(store.burnt, id)
+		}
+	}
+
+	// Removed attachments only need remembering while their pads are at the revision they were removed at.
+	for id, removed := range store.removedAttachments {
+		for attachment, revision := range removed {
+			if pad, ok := store.pads[id]; !ok || pad.Revision != revision {
+				delete(removed, attachment)
+			}
+		}
+
+		if len(removed) == 0 {
+			delete(store.removedAttachments, id)
+		}
+	}
+
+	return
+}
+
+// Usage counts the pads stored, and the bytes of their content (or chunks), attachments and uploads' staged chunks.
+func (store *MemoryStore) Usage() (usage model.Usage, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for id, pad := range store.pads {
+		usage.Pads++
+		usage.Bytes += pad.Size
+
+		for _, attachment := range store.attachments[id] {
+			usage.Bytes += attachment.Size
+		}
+	}
+
+	for _, chunks := range store.uploadChunks {
+		for _, data := range chunks {
+			usage.Bytes += int64(len(data))
+		}
+	}
+
+	return
+}
+
+// Burn atomically reads and removes a pad, leaving a record that it was burnt.
+func (store *MemoryStore) Burn(id string) (pad model.Pad, err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	pad, ok := store.pads[id]
+	if !ok {
+		err = sql.ErrNoRows
+		return
+	}
+
+	store.remove(id)
+	store.burnt[id] = time.Now().Add(burntRetention).Unix()
+	return
+}
+
+// Burnt checks if a pad has been burnt.
+func (store *MemoryStore) Burnt(id string) (burnt bool, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	_, burnt = store.burnt[id]
+	return
+}
+
+// Editors lists the editors of a pad.
+func (store *MemoryStore) Editors(id string) (editors []model.Editor, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, editor := range store.editors[id] {
+		editors = append(editors, editor)
+	}
+
+	sort.Slice(editors, func(i, j int) bool {
+		return editors[i].Name < editors[j].Name
+	})
+
+	return
+}
+
+// Editor gets an editor of a pad by name.
+func (store *MemoryStore) Editor(id, name string) (editor model.Editor, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	editor, ok := store.editors[id][name]
+	if !ok {
+		err = sql.ErrNoRows
+	}
+
+	return
+}
+
+// PutEditor adds an editor to a pad, or replaces the editor's proof.
+func (store *MemoryStore) PutEditor(id string, editor model.Editor) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.editors[id] == nil {
+		store.editors[id] = make(map[string]model.Editor)
+	}
+
+	store.editors[id][editor.Name] = editor
+	return
+}
+
+// RemoveEditor removes an editor from a pad.
+func (store *MemoryStore) RemoveEditor(id, name string) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.editors[id], name)
+	return
+}
+
+// Lockout gets how many incorrect proofs a pad has been sent in a row, and when it is locked until.
+func (store *MemoryStore) Lockout(id string) (lockout model.Lockout, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	lockout, ok := store.lockouts[id]
+	if !ok {
+		err = sql.ErrNoRows
+	}
+
+	return
+}
+
+// AddFailure counts another incorrect proof sent to a pad.
+func (store *MemoryStore) AddFailure(id string) (lockout model.Lockout, err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	lockout = store.lockouts[id]
+	lockout.Failures++
+	store.lockouts[id] = lockout
+	return
+}
+
+// LockPad locks a pad until a unix timestamp.
+func (store *MemoryStore) LockPad(id string, until int64) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if lockout, ok := store.lockouts[id]; ok {
+		lockout.LockedUntil = until
+		store.lockouts[id] = lockout
+	}
+
+	return
+}
+
+// RemoveLockout forgets a pad's incorrect proofs.
+func (store *MemoryStore) RemoveLockout(id string) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.lockouts, id)
+	return
+}
+
+// Revisions lists the stored revisions of a pad, oldest first.
+func (store *MemoryStore) Revisions(id string) (revisions []model.Revision, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, revision := range store.revisions[id] {
+		revisions = append(revisions, revision.Revision)
+	}
+
+	return
+}
+
+// Revision gets a stored revision of a pad.
+func (store *MemoryStore) Revision(id string, number int64) (pad model.Pad, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, revision := range store.revisions[id] {
+		if revision.Revision.Revision == number {
+			pad = model.Pad{
+				ID:       id,
+				Content:  revision.Content,
+				Revision: number,
+			}
+
+			return
+		}
+	}
+
+	err = sql.ErrNoRows
+	return
+}
+
+// Ops lists the ops of a pad after a sequence, oldest first.
+func (store *MemoryStore) Ops(id string, since int64) (ops []model.Op, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, op := range store.ops[id] {
+		if op.Sequence > since {
+			ops = append(ops, op)
+		}
+	}
+
+	return
+}
+
+// OpSequence gets the sequence of the last op of a pad, whether it is still in the log or compacted.
+func (store *MemoryStore) OpSequence(id string) (sequence int64, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.opSequence(id)
+}
+
+// AppendOp adds an op to the end of a pad's log, if its sequence directly follows the last op.
+func (store *MemoryStore) AppendOp(id string, op model.Op) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	sequence, err := store.opSequence(id)
+	if err != nil {
+		return
+	}
+
+	if op.Sequence != sequence+1 {
+		err = errStaleSequence
+		return
+	}
+
+	store.ops[id] = append(store.ops[id], op)
+	return
+}
+
+// opSequence gets the sequence of the last op of a pad. The mutex must already be locked.
+func (store *MemoryStore) opSequence(id string) (sequence int64, err error) {
+	pad, ok := store.pads[id]
+	if !ok {
+		err = sql.ErrNoRows
+		return
+	}
+
+	sequence = pad.Compacted
+	if ops := store.ops[id]; len(ops) != 0 && ops[len(ops)-1].Sequence > sequence {
+		sequence = ops[len(ops)-1].Sequence
+	}
+
+	return
+}
+
+// CreateUpload starts an upload of a pad's chunks, if its ID isn't taken and the pad doesn't have max open uploads.
+func (store *MemoryStore) CreateUpload(upload model.Upload, max int) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, ok := store.uploads[upload.ID]; ok {
+		err = errUploadExists
+		return
+	}
+
+	if max > 0 {
+		now := time.Now().Unix()
+
+		open := 0
+		for _, other := range store.uploads {
+			if other.Pad == upload.Pad && other.ExpiresAt > now {
+				open++
+			}
+		}
+
+		if open >= max {
+			err = errTooManyUploads
+			return
+		}
+	}
+
+	store.uploads[upload.ID] = upload
+	store.uploadChunks[upload.ID] = make(map[int][]byte)
+	return
+}
+
+// Upload gets an upload given its ID.
+func (store *MemoryStore) Upload(id string) (upload model.Upload, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	upload, ok := store.uploads[id]
+	if !ok {
+		err = sql.ErrNoRows
+	}
+
+	return
+}
+
+// PutChunk stores a chunk of an upload, replacing it if it was already received,
+// unless the upload's chunks would add up to more than its size.
+func (store *MemoryStore) PutChunk(upload string, index int, data []byte) (staged int64, err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	chunks, ok := store.uploadChunks[upload]
+	if !ok {
+		err = sql.ErrNoRows
+		return
+	}
+
+	size := int64(len(data))
+	for other, received := range chunks {
+		if other != index {
+			size += int64(len(received))
+		}
+	}
+
+	if size > store.uploads[upload].Size {
+		err = errPadTooLarge
+		return
+	}
+
+	staged = int64(len(data) - len(chunks[index]))
+	chunks[index] = data
+	return
+}
+
+// UploadChunks lists the chunks an upload has received, in order.
+func (store *MemoryStore) UploadChunks(upload string) (chunks []model.Chunk, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for index, data := range store.uploadChunks[upload] {
+		chunks = append(chunks, model.Chunk{
+			Index: index,
+			Size:  int64(len(data)),
+			Hash:  hashHex(data),
+		})
+	}
+
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].Index < chunks[j].Index
+	})
+
+	return
+}
+
+// CommitUpload replaces a pad's content with an upload's chunks, if the pad is still at the upload's revision.
+func (store *MemoryStore) CommitUpload(upload model.Upload) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	pad, ok := store.pads[upload.Pad]
+	if !ok || pad.Revision != upload.Revision {
+		err = errStaleRevision
+		return
+	}
+
+	chunks := make([][]byte, upload.Chunks)
+	for index, data := range store.uploadChunks[upload.ID] {
+		if index < len(chunks) {
+			chunks[index] = data
+		}
+	}
+
+	pad.Content = ""
+	pad.Chunks = upload.Chunks
+	pad.Size = upload.Size
+	pad.Revision++
+
+	store.pads[pad.ID] = pad
+	store.chunks[pad.ID] = chunks
+	delete(store.uploads, upload.ID)
+	delete(store.uploadChunks, upload.ID)
+	return
+}
+
+// Chunk gets a chunk of a pad's content.
+func (store *MemoryStore) Chunk(id string, index int) (data []byte, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	chunks := store.chunks[id]
+	if index < 0 || index >= len(chunks) {
+		err = sql.ErrNoRows
+		return
+	}
+
+	data = chunks[index]
+	return
+}
+
+// Attachments lists the attachments of a pad, oldest first.
+func (store *MemoryStore) Attachments(id string) (attachments []model.Attachment, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, attachment := range store.attachments[id] {
+		attachments = append(attachments, attachment.Attachment)
+	}
+
+	return
+}
+
+// Attachment gets an attachment of a pad and its data.
+func (store *MemoryStore) Attachment(id, attachmentID string) (attachment model.Attachment, data []byte, err error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, stored := range store.attachments[id] {
+		if stored.ID == attachmentID {
+			attachment = stored.Attachment
+			data = stored.Data
+			return
+		}
+	}
+
+	err = sql.ErrNoRows
+	return
+}
+
+// PutAttachment adds an attachment to a pad, if it doesn't have (or hasn't had) the attachment or max attachments.
+func (store *MemoryStore) PutAttachment(id string, added model.Attachment, data []byte, max int) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, ok := store.removedAttachments[id][added.ID]; ok {
+		err = errAttachmentExists
+		return
+	}
+
+	for _, stored := range store.attachments[id] {
+		if stored.ID == added.ID {
+			err = errAttachmentExists
+			return
+		}
+	}
+
+	if len(store.attachments[id]) >= max {
+		err = errTooManyAttachments
+		return
+	}
+
+	store.attachments[id] = append(store.attachments[id], attachment{
+		Attachment: added,
+		Data:       data,
+	})
+
+	return
+}
+
+// RemoveAttachment removes an attachment from a pad, remembering its ID at the pad's revision.
+func (store *MemoryStore) RemoveAttachment(id, attachmentID string) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	pad, ok := store.pads[id]
+	if !ok {
+		return
+	}
+
+	if store.removedAttachments[id] == nil {
+		store.removedAttachments[id] = make(map[string]int64)
+	}
+
+	store.removedAttachments[id][attachmentID] = pad.Revision
+
+	attachments := store.attachments[id]
+	for i, stored := range attachments {
+		if stored.ID == attachmentID {
+			store.attachments[id] = append(attachments[:i:i], attachments[i+1:]...)
+			return
+		}
+	}
+
+	return
+}
+
+// remove removes a pad and everything stored with it. The mutex must already be locked.
+func (store *MemoryStore) remove(id string) {
+	delete(store.pads, id)
+	delete(store.revisions, id)
+	delete(store.editors, id)
+	delete(store.ops, id)
+	delete(store.chunks, id)
+	delete(store.attachments, id)
+	delete(store.removedAttachments, id)
+	delete(store.lockouts, id)
+
+	for upload, other := range store.uploads {
+		if other.Pad == id {
+			delete(store.uploads, upload)
+			delete(store.uploadChunks, upload)
+		}
+	}
+}
+
+// record stores a revision of a pad, removing any revisions older than the history allows.
+// The mutex must already be locked.
+func (store *MemoryStore) record(pad model.Pad) {
+	if store.History <= 0 {
+		return
+	}
+
+	revisions := append(store.revisions[pad.ID], revision{
+		Revision: model.Revision{
+			Revision: pad.Revision,
+			Created:  time.Now().Unix(),
+		},
+		Content: pad.Content,
+	})
+
+	if len(revisions) > store.History {
+		revisions = revisions[len(revisions)-store.History:]
+	}
+
+	store.revisions[pad.ID] = revisions
+}
+
+// stored converts a pad from a request into the form it is stored in.
+func stored(pad model.Pad) model.Pad {
+	return model.Pad{
+		ID:        pad.ID,
+		Content:   pad.Content,
+		Proof:     pad.NewProof,
+		Revision:  pad.Revision,
+		ExpiresAt: pad.ExpiresAt,
+
+		BurnAfterReading: pad.BurnAfterReading,
+		PublicKey:        pad.PublicKey,
+		ReadToken:        pad.ReadToken,
+		Compacted:        pad.Compacted,
+		Size:             pad.Size,
+	}
+}
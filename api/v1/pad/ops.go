@@ -0,0 +1,232 @@
+package pad
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// EventOp is published when an op is appended to a pad's log.
+	EventOp = "op"
+)
+
+var (
+	errInvalidOpLen      = fmt.Errorf("ops must be between 1 and %v in length", model.ContentLen.Max)
+	errInvalidSequence   = errors.New("sequence must not be negative")
+	errBurnOps           = errors.New("burn after reading pads can not have ops")
+	errOpLogFull         = errors.New("op log is full, compact it into the content first")
+	errCompacted         = errors.New("ops have been compacted into the content since that sequence")
+	errStaleCompaction   = errors.New("ops have already been compacted past that sequence")
+	errInvalidCompaction = errors.New("can only compact ops which have been appended")
+)
+
+// Ops lists the ops of a pad which haven't been compacted into its content.
+// Clients can list only the ops after a sequence with the since query parameter, which defaults to the last compacted op.
+func Ops(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(id) {
+		helper.ThrowErr(errInvalidIDLen, http.StatusBadRequest, w)
+		return
+	}
+
+	pad, err := FromID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	// Otherwise the content could be read without burning the pad.
+	if pad.BurnAfterReading {
+		helper.ThrowErr(errBurnOps, http.StatusForbidden, w)
+		return
+	}
+
+	err = authoriseRead(pad, r)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusForbidden, w)
+		return
+	}
+
+	since := pad.Compacted
+	if query := r.URL.Query().Get("since"); query != "" {
+		since, err = strconv.ParseInt(query, 10, 64)
+		if err != nil || since < 0 {
+			helper.ThrowErr(errInvalidSequence, http.StatusBadRequest, w)
+			return
+		}
+	}
+
+	// Ops before the last compaction are gone, so the client must get the pad again.
+	if since < pad.Compacted {
+		helper.ThrowErr(errCompacted, http.StatusGone, w)
+		return
+	}
+
+	ops, err := Store.Ops(id, since)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	if ops == nil {
+		ops = []model.Op{}
+	}
+
+	helper.Response(ops, http.StatusOK, w)
+}
+
+// AppendOp adds an encrypted op, sent as the content, to the end of a pad's log.
+// The owner or an editor must authorise the request, and can send the sequence they expect the op to get.
+func AppendOp(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(id) {
+		helper.ThrowErr(errInvalidIDLen, http.StatusBadRequest, w)
+		return
+	}
+
+	// Get data from the request, in whichever format it was sent.
+	var data model.Pad
+	err := decode(w, r, &data)
+	if err != nil {
+		helper.ThrowErr(err, helper.DecodeErrStatus(err), w)
+		return
+	}
+
+	// Check if the op is a valid length.
+	if !model.ContentLen.Check(string(data.Content)) || data.Content == "" {
+		helper.ThrowErr(errInvalidOpLen, http.StatusBadRequest, w)
+		return
+	}
+
+	if data.Sequence < 0 {
+		helper.ThrowErr(errInvalidSequence, http.StatusBadRequest, w)
+		return
+	}
+
+	// Check if the proof is a valid length, or there is a challenge response instead.
+	err = checkAuthLen(data)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
+		return
+	}
+
+	pad, err := FromID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	if pad.BurnAfterReading {
+		helper.ThrowErr(errBurnOps, http.StatusForbidden, w)
+		return
+	}
+
+	last, err := Store.OpSequence(id)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	op := model.Op{
+		Sequence: last + 1,
+		Content:  data.Content,
+		Created:  time.Now().Unix(),
+	}
+
+	// Another op was appended since the client last saw the log.
+	if data.Sequence != 0 && data.Sequence != op.Sequence {
+		helper.ThrowErr(errStaleSequence, http.StatusConflict, w)
+		return
+	}
+
+	if cfg.MaxOps > 0 && op.Sequence-pad.Compacted > int64(cfg.MaxOps) {
+		helper.ThrowErr(errOpLogFull, http.StatusConflict, w)
+		return
+	}
+
+	// Both the owner and editors can append ops, as they only change the content.
+	_, err = authorise(pad, data, opMessage(id, data.Content, op.Sequence))
+	if err != nil {
+		throwAuthErr(err, w)
+		return
+	}
+
+	err = Store.AppendOp(id, op)
+	if err != nil {
+		if err == errStaleSequence {
+			helper.ThrowErr(err, http.StatusConflict, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	publish(model.Event{
+		Type:     EventOp,
+		ID:       id,
+		Sequence: op.Sequence,
+		Content:  op.Content,
+	})
+
+	op.Content = ""
+	helper.Response(op, http.StatusCreated, w)
+}
+
+// compact checks an update compacting ops into its content, keeping the current compaction if it isn't compacting.
+func compact(pad model.Pad, data *model.Pad) (err error) {
+	if data.Compacted == 0 {
+		data.Compacted = pad.Compacted
+		return
+	}
+
+	if data.Compacted < pad.Compacted {
+		err = errStaleCompaction
+		return
+	}
+
+	last, err := Store.OpSequence(pad.ID)
+	if err != nil {
+		return
+	}
+
+	if data.Compacted > last {
+		err = errInvalidCompaction
+	}
+
+	return
+}
+
+// compactErrStatus gets the status code for an error from updating a pad, which may have been compacting ops.
+func compactErrStatus(err error) int {
+	switch err {
+	case errStaleCompaction:
+		return http.StatusConflict
+	case errInvalidCompaction:
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
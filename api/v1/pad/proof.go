@@ -0,0 +1,109 @@
+package pad
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// hashPrefix marks a stored proof as an Argon2id hash rather than a legacy plaintext proof.
+	hashPrefix = "$argon2id$"
+
+	hashTime    = 2
+	hashMemory  = 19 * 1024
+	hashThreads = 1
+	hashSaltLen = 16
+	hashKeyLen  = 32
+)
+
+var (
+	errInvalidProofHash = errors.New("stored proof hash is malformed")
+)
+
+// HashProof hashes a proof with Argon2id and a random salt.
+// The parameters are encoded alongside the hash so they can be changed without breaking existing pads.
+func HashProof(proof string) (hash string, err error) {
+	salt := make([]byte, hashSaltLen)
+	_, err = rand.Read(salt)
+	if err != nil {
+		return
+	}
+
+	key := argon2.IDKey([]byte(proof), salt, hashTime, hashMemory, hashThreads, hashKeyLen)
+
+	hash = fmt.Sprintf(
+		"%vv=%v$m=%v,t=%v,p=%v$%v$%v",
+		hashPrefix,
+		argon2.Version,
+		hashMemory,
+		hashTime,
+		hashThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	return
+}
+
+// VerifyProof checks in constant time whether a proof matches a stored proof.
+// Stored proofs from before hashing was introduced are plaintext, and are compared directly.
+// Pads owned by a public key have no stored proof, which nothing matches.
+func VerifyProof(stored, proof string) bool {
+	if stored == "" || proof == "" {
+		return false
+	}
+
+	if !strings.HasPrefix(stored, hashPrefix) {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(proof)) == 1
+	}
+
+	match, err := verifyHash(stored, proof)
+	return err == nil && match
+}
+
+func verifyHash(stored, proof string) (match bool, err error) {
+	// The hash is in the form $argon2id$v=19$m=...,t=...,p=...$salt$key.
+	parts := strings.Split(stored, "$")
+	if len(parts) != 6 {
+		err = errInvalidProofHash
+		return
+	}
+
+	var version int
+	_, err = fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil {
+		return
+	}
+
+	if version != argon2.Version {
+		err = errInvalidProofHash
+		return
+	}
+
+	var memory, time uint32
+	var threads uint8
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads)
+	if err != nil {
+		return
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return
+	}
+
+	other := argon2.IDKey([]byte(proof), salt, time, memory, threads, uint32(len(key)))
+	match = subtle.ConstantTimeCompare(key, other) == 1
+	return
+}
@@ -0,0 +1,210 @@
+package pad
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/ratelimit"
+)
+
+const (
+	// quotaWindow is how far back pads created from each address are counted.
+	quotaWindow = 24 * time.Hour
+
+	// usageLifetime is how long the store's usage is cached for, so it isn't counted on every write.
+	usageLifetime = 10 * time.Second
+)
+
+var (
+	errFull          = errors.New("server has no room for new pads")
+	errReadOnly      = errors.New("server is nearly full, so it is read only")
+	errQuotaExceeded = errors.New("too many pads have been created from this address today")
+)
+
+var (
+	quotaMutex sync.Mutex
+
+	// usage is the store's usage when it was last counted, plus anything created since.
+	usage     model.Usage
+	usageRead time.Time
+
+	// addressCreations are when pads were created from each address within the quota window, oldest first.
+	addressCreations = make(map[string][]time.Time)
+	addressesSwept   time.Time
+)
+
+// checkCapacity checks the store has room for more data.
+// Once it is nearly full (ReadOnlyAt of MaxBytes) nothing can be added to it, though pads can still be read, updated and deleted.
+func checkCapacity() (err error) {
+	if cfg.MaxBytes <= 0 {
+		return
+	}
+
+	quotaMutex.Lock()
+	defer quotaMutex.Unlock()
+
+	current, err := currentUsage()
+	if err != nil {
+		return
+	}
+
+	readOnlyAt := cfg.ReadOnlyAt
+	if readOnlyAt <= 0 || readOnlyAt > 1 {
+		readOnlyAt = 1
+	}
+
+	if float64(current.Bytes) >= float64(cfg.MaxBytes)*readOnlyAt {
+		err = errReadOnly
+	}
+
+	return
+}
+
+// checkQuota checks a new pad can be created from a request's address, and that the store has room for it.
+// If it can, the pad is counted against the address in the same lock, so concurrent requests can't go over the quota.
+// releaseQuota must be called if the pad isn't created after all.
+func checkQuota(r *http.Request) (err error) {
+	err = checkCapacity()
+	if err != nil {
+		return
+	}
+
+	quotaMutex.Lock()
+	defer quotaMutex.Unlock()
+
+	if cfg.MaxPads > 0 {
+		var current model.Usage
+		current, err = currentUsage()
+		if err != nil {
+			return
+		}
+
+		if current.Pads >= cfg.MaxPads {
+			return errFull
+		}
+	}
+
+	if cfg.MaxPadsPerIP > 0 {
+		address := ratelimit.ClientIP(r)
+
+		created := recentAddressCreations(address)
+		if len(created) >= cfg.MaxPadsPerIP {
+			return errQuotaExceeded
+		}
+
+		addressCreations[address] = append(created, time.Now())
+	}
+
+	return
+}
+
+// quotaErrStatus gets the status code for an error from checking quotas.
+func quotaErrStatus(err error) int {
+	switch err {
+	case errFull, errReadOnly:
+		return http.StatusInsufficientStorage
+	case errQuotaExceeded:
+		return http.StatusTooManyRequests
+	}
+
+	return http.StatusInternalServerError
+}
+
+// releaseQuota stops counting a pad against a request's address, when it wasn't created after checking the quota.
+func releaseQuota(r *http.Request) {
+	if cfg.MaxPadsPerIP <= 0 {
+		return
+	}
+
+	quotaMutex.Lock()
+	defer quotaMutex.Unlock()
+
+	address := ratelimit.ClientIP(r)
+
+	created := addressCreations[address]
+	if len(created) <= 1 {
+		delete(addressCreations, address)
+		return
+	}
+
+	addressCreations[address] = created[:len(created)-1]
+}
+
+// addUsage adds pads and bytes stored to the cached usage, so they are included before it is counted again.
+// Bytes can be negative, when content is replaced with something smaller.
+func addUsage(pads, bytes int64) {
+	quotaMutex.Lock()
+	defer quotaMutex.Unlock()
+
+	usage.Pads += pads
+	usage.Bytes += bytes
+}
+
+// forgetUsage forgets the cached usage, so it is counted again when it is next needed.
+func forgetUsage() {
+	quotaMutex.Lock()
+	defer quotaMutex.Unlock()
+
+	usageRead = time.Time{}
+}
+
+// forgetAddressCreations forgets when pads were created from every address.
+func forgetAddressCreations() {
+	quotaMutex.Lock()
+	defer quotaMutex.Unlock()
+
+	addressCreations = make(map[string][]time.Time)
+}
+
+// currentUsage gets the store's usage, counting it again if the cached usage is too old.
+// The mutex must already be locked.
+func currentUsage() (model.Usage, error) {
+	if time.Since(usageRead) < usageLifetime {
+		return usage, nil
+	}
+
+	counted, err := Store.Usage()
+	if err != nil {
+		return counted, err
+	}
+
+	usage = counted
+	usageRead = time.Now()
+	return usage, nil
+}
+
+// recentAddressCreations forgets creations from an address from before the quota window, and gets the rest.
+// Addresses which haven't created a pad recently are forgotten every so often, so they don't use memory.
+// The mutex must already be locked.
+func recentAddressCreations(address string) []time.Time {
+	since := time.Now().Add(-quotaWindow)
+
+	if addressesSwept.Before(since) {
+		for other, created := range addressCreations {
+			if created[len(created)-1].Before(since) {
+				delete(addressCreations, other)
+			}
+		}
+
+		addressesSwept = time.Now()
+	}
+
+	created := addressCreations[address]
+
+	old := 0
+	for old < len(created) && created[old].Before(since) {
+		old++
+	}
+
+	created = created[old:]
+	if len(created) == 0 {
+		delete(addressCreations, address)
+		return nil
+	}
+
+	addressCreations[address] = created
+	return created
+}
@@ -0,0 +1,189 @@
+package pad
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+	"github.com/VolticFroogo/cryptopad-server/ratelimit"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// reservationLifetime is how long an ID is held for a client while it derives keys.
+	reservationLifetime = 5 * time.Minute
+
+	// maxReservationsPerIP is how many IDs each address can hold at once.
+	maxReservationsPerIP = 10
+
+	reservationTokenLen = 32
+)
+
+var (
+	errUnavailable = errors.New("pad id is already taken or reserved")
+	errReserved    = errors.New("pad id is reserved by another client")
+
+	errTooManyReservations = errors.New("too many pad ids are reserved from this address")
+)
+
+var (
+	reservationsMutex sync.Mutex
+	reservations      = make(map[string]reservation)
+)
+
+// reservation is a reserved ID, along with the address it was reserved from.
+type reservation struct {
+	model.Reservation
+	Address string
+}
+
+// Available checks if a pad ID can be used to create a new pad.
+func Available(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(id) {
+		helper.ThrowErr(errInvalidIDLen, http.StatusBadRequest, w)
+		return
+	}
+
+	available, err := available(id, "")
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	helper.Response(model.Availability{
+		ID:        id,
+		Available: available,
+	}, http.StatusOK, w)
+}
+
+// Reserve holds an unused pad ID for a client for a short time, so nobody else can create it.
+func Reserve(w http.ResponseWriter, r *http.Request) {
+	// Get data from the request, in whichever format it was sent.
+	var data model.Reservation
+	err := decode(w, r, &data)
+	if err != nil {
+		helper.ThrowErr(err, helper.DecodeErrStatus(err), w)
+		return
+	}
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(data.ID) {
+		helper.ThrowErr(errInvalidIDLen, http.StatusBadRequest, w)
+		return
+	}
+
+	token := make([]byte, reservationTokenLen)
+	_, err = rand.Read(token)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	reserved := reservation{
+		Reservation: model.Reservation{
+			ID:        data.ID,
+			Token:     base64.RawURLEncoding.EncodeToString(token),
+			ExpiresAt: time.Now().Add(reservationLifetime).Unix(),
+		},
+		Address: ratelimit.ClientIP(r),
+	}
+
+	// Hold the lock while checking, so two clients can't both reserve the ID.
+	reservationsMutex.Lock()
+	defer reservationsMutex.Unlock()
+
+	// Forget any reservations which were never claimed, and count the rest held by the address.
+	held := 0
+	for key, other := range reservations {
+		if time.Now().Unix() >= other.ExpiresAt {
+			delete(reservations, key)
+			continue
+		}
+
+		if other.Address == reserved.Address {
+			held++
+		}
+	}
+
+	if held >= maxReservationsPerIP {
+		helper.ThrowErr(errTooManyReservations, http.StatusTooManyRequests, w)
+		return
+	}
+
+	ok, err := availableLocked(data.ID, "")
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	if !ok {
+		helper.ThrowErr(errUnavailable, http.StatusConflict, w)
+		return
+	}
+
+	reservations[data.ID] = reserved
+
+	helper.Response(reserved.Reservation, http.StatusCreated, w)
+}
+
+// available checks if a pad doesn't exist and isn't reserved, other than by the given reservation token.
+func available(id, token string) (ok bool, err error) {
+	reservationsMutex.Lock()
+	defer reservationsMutex.Unlock()
+
+	return availableLocked(id, token)
+}
+
+// availableLocked is available, but the reservations mutex must already be locked.
+func availableLocked(id, token string) (ok bool, err error) {
+	_, err = FromID(id)
+	if err == nil {
+		return
+	}
+
+	if err != sql.ErrNoRows {
+		return
+	}
+
+	err = nil
+	ok = !reservedLocked(id, token)
+	return
+}
+
+// reservedLocked checks if an ID is reserved, other than by the given reservation token.
+// Expired reservations are removed. The reservations mutex must already be locked.
+func reservedLocked(id, token string) bool {
+	reservation, ok := reservations[id]
+	if !ok {
+		return false
+	}
+
+	if time.Now().Unix() >= reservation.ExpiresAt {
+		delete(reservations, id)
+		return false
+	}
+
+	return reservation.Token != token
+}
+
+// claim checks an ID isn't reserved by another client before creating a pad, and uses up the reservation.
+func claim(id, token string) error {
+	reservationsMutex.Lock()
+	defer reservationsMutex.Unlock()
+
+	if reservedLocked(id, token) {
+		return errReserved
+	}
+
+	delete(reservations, id)
+	return nil
+}
@@ -0,0 +1,58 @@
+package pad
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+)
+
+// TestReserveLimit tests expired reservations are forgotten, and each address can only hold so many.
+func TestReserveLimit(t *testing.T) {
+	defer func(previous PadStore, held map[string]reservation) {
+		Store = previous
+		reservations = held
+	}(Store, reservations)
+
+	Store = NewMemoryStore(0)
+	reservations = map[string]reservation{
+		"expired": {
+			Reservation: model.Reservation{ID: "expired", ExpiresAt: time.Now().Unix() - 1},
+			Address:     "192.0.2.1",
+		},
+	}
+
+	reserve := func(id, address string) int {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/reservation", strings.NewReader(`{"ID":"`+id+`"}`))
+		r.RemoteAddr = address + ":1234"
+
+		w := httptest.NewRecorder()
+		Reserve(w, r)
+		return w.Code
+	}
+
+	for i := 0; i < maxReservationsPerIP; i++ {
+		status := reserve("reserved-"+strconv.Itoa(i), "192.0.2.1")
+		if status != http.StatusCreated {
+			t.Fatalf("reserve limit: reservation %v was %v", i, status)
+		}
+	}
+
+	if _, ok := reservations["expired"]; ok {
+		t.Error("reserve limit: expired reservation wasn't forgotten")
+	}
+
+	status := reserve("reserved-over", "192.0.2.1")
+	if status != http.StatusTooManyRequests {
+		t.Errorf("reserve limit: reservation over the limit was %v", status)
+	}
+
+	status = reserve("reserved-other", "192.0.2.2")
+	if status != http.StatusCreated {
+		t.Errorf("reserve limit: reservation from another address was %v", status)
+	}
+}
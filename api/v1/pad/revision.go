@@ -0,0 +1,199 @@
+package pad
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+	"github.com/gorilla/mux"
+)
+
+var (
+	errInvalidRevision = errors.New("revision must be a positive number")
+	errBurnHistory     = errors.New("revisions of burn after reading pads can not be read")
+)
+
+// Revisions lists the stored revisions of a pad.
+func Revisions(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(id) {
+		helper.ThrowErr(errInvalidIDLen, http.StatusBadRequest, w)
+		return
+	}
+
+	// Check the pad exists (and hasn't expired).
+	pad, err := FromID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	err = authoriseRead(pad, r)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusForbidden, w)
+		return
+	}
+
+	revisions, err := Store.Revisions(id)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	helper.Response(revisions, http.StatusOK, w)
+}
+
+// Revision gets the content of a stored revision of a pad.
+func Revision(w http.ResponseWriter, r *http.Request) {
+	var revision model.Pad
+
+	id, number, err := revisionVars(r)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
+		return
+	}
+
+	// Check the pad exists (and hasn't expired).
+	pad, err := FromID(id)
+	if err == nil {
+		// Otherwise old revisions could be read without burning the pad.
+		if pad.BurnAfterReading {
+			helper.ThrowErr(errBurnHistory, http.StatusForbidden, w)
+			return
+		}
+
+		err = authoriseRead(pad, r)
+		if err != nil {
+			helper.ThrowErr(err, http.StatusForbidden, w)
+			return
+		}
+
+		revision, err = Store.Revision(id, number)
+	}
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	revision.Envelope = envelopeVersion(revision.Content)
+
+	w.Header().Set("ETag", etag(revision.Revision))
+	helper.Response(revision, http.StatusOK, w)
+}
+
+// Restore makes a stored revision the current content of a pad.
+func Restore(w http.ResponseWriter, r *http.Request) {
+	id, number, err := revisionVars(r)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
+		return
+	}
+
+	// Get data from the request, in whichever format it was sent.
+	var data model.Pad
+	err = decode(w, r, &data)
+	if err != nil {
+		helper.ThrowErr(err, helper.DecodeErrStatus(err), w)
+		return
+	}
+
+	// Check if the proof is a valid length, or there is a challenge response instead.
+	err = checkAuthLen(data)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
+		return
+	}
+
+	pad, err := FromID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch != "" && !matchesRevision(ifMatch, pad.Revision) {
+		w.Header().Set("ETag", etag(pad.Revision))
+		helper.ThrowErr(errRevisionMismatch, http.StatusPreconditionFailed, w)
+		return
+	}
+
+	revision, err := Store.Revision(id, number)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	// Restoring is an update to the old content, so it becomes a new revision.
+	data.ID = id
+	data.Content = revision.Content
+	data.NewProof = ""
+	data.NewPublicKey = ""
+	data.NewReadToken = ""
+	data.ExpiresAt = 0
+	data.BurnAfterReading = false
+	data.Compacted = 0
+
+	err = updateIfTrusted(pad, data)
+	if err != nil {
+		if isAuthErr(err) {
+			throwAuthErr(err, w)
+			return
+		}
+
+		if err == errStaleRevision {
+			helper.ThrowErr(err, http.StatusConflict, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	w.Header().Set("ETag", etag(pad.Revision+1))
+	w.WriteHeader(http.StatusOK)
+}
+
+// revisionVars gets and validates the pad ID and revision number from a request's URL.
+func revisionVars(r *http.Request) (id string, revision int64, err error) {
+	vars := mux.Vars(r)
+	id = vars["id"]
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(id) {
+		err = errInvalidIDLen
+		return
+	}
+
+	revision, err = strconv.ParseInt(vars["rev"], 10, 64)
+	if err != nil || revision < 1 {
+		err = errInvalidRevision
+	}
+
+	return
+}
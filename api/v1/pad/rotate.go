@@ -0,0 +1,239 @@
+package pad
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+	"github.com/gorilla/mux"
+)
+
+var (
+	errIncompleteRotation  = errors.New("rotations must re-encrypt every kept revision and attachment, and nothing else")
+	errUncompactedRotation = errors.New("rotations must compact every op into the content")
+	errChunkedRotation     = errors.New("pads uploaded in chunks can't be rotated")
+	errNoNewReadToken      = errors.New("rotations of pads with a read token must send a new read token, or make the pad public")
+	errPublicReadToken     = errors.New("rotations can't both send a new read token and make the pad public")
+)
+
+// RotateKey re-encrypts a pad with a new key, replacing its proof, content, history and attachments at once.
+// Only the owner can rotate a pad, and everything authorised by the old key (editors, read tokens,
+// challenges, uploads and subscribers) stops working, unless a new read token or public key is sent.
+// Pads with a read token keep one, unless the rotation explicitly makes them public.
+func RotateKey(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(id) {
+		helper.ThrowErr(errInvalidIDLen, http.StatusBadRequest, w)
+		return
+	}
+
+	// Get data from the request, in whichever format it was sent.
+	// Rotations carry every revision and attachment, so they can be much larger than other requests.
+	var data model.Rotation
+	r.Body = http.MaxBytesReader(w, r.Body, rotationBodySize())
+	err := helper.Decode(r, &data)
+	if err != nil {
+		helper.ThrowErr(err, helper.DecodeErrStatus(err), w)
+		return
+	}
+
+	data.ID = id
+
+	err = checkRotation(data)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
+		return
+	}
+
+	pad, err := FromID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch != "" && !matchesRevision(ifMatch, pad.Revision) {
+		w.Header().Set("ETag", etag(pad.Revision))
+		helper.ThrowErr(errRevisionMismatch, http.StatusPreconditionFailed, w)
+		return
+	}
+
+	owner, err := authorise(pad, data.Pad, rotateMessage(data, pad.Revision))
+	if err == nil && !owner {
+		err = errNotOwner
+	}
+
+	if err != nil {
+		throwAuthErr(err, w)
+		return
+	}
+
+	// The old read token is revoked, so the pad must not become readable by anyone unless that was asked for.
+	if pad.ReadToken != "" && data.NewReadToken == "" && !data.Public {
+		helper.ThrowErr(errNoNewReadToken, http.StatusBadRequest, w)
+		return
+	}
+
+	// Rotations only carry inline content, so chunks would be left encrypted with the old key.
+	// Committing an upload changes the revision, so the store rejects the rotation if the pad is chunked since.
+	if pad.Chunks > 0 {
+		helper.ThrowErr(errChunkedRotation, http.StatusConflict, w)
+		return
+	}
+
+	// Ops are encrypted with the old key too, so they must all be compacted into the new content.
+	last, err := Store.OpSequence(id)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	if data.Compacted != last {
+		helper.ThrowErr(errUncompactedRotation, http.StatusConflict, w)
+		return
+	}
+
+	err = checkRotated(id, data)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
+		return
+	}
+
+	rotated := model.Pad{
+		ID:               id,
+		Content:          data.Content,
+		NewProof:         data.NewProof,
+		Revision:         pad.Revision,
+		ExpiresAt:        pad.ExpiresAt,
+		BurnAfterReading: pad.BurnAfterReading,
+		PublicKey:        data.NewPublicKey,
+		Compacted:        data.Compacted,
+	}
+
+	if data.NewReadToken != "" {
+		rotated.ReadToken = hashReadToken(data.NewReadToken)
+	}
+
+	err = Rotate(rotated, data.Revisions, data.Attachments)
+	if err != nil {
+		switch err {
+		case errStaleRevision:
+			// The pad was updated, or a revision or attachment was added, since it was read.
+			helper.ThrowErr(err, http.StatusConflict, w)
+		case errStaleSequence:
+			helper.ThrowErr(errUncompactedRotation, http.StatusConflict, w)
+		default:
+			helper.ThrowErr(err, http.StatusInternalServerError, w)
+		}
+
+		return
+	}
+
+	w.Header().Set("ETag", etag(pad.Revision+1))
+	w.WriteHeader(http.StatusOK)
+}
+
+// checkRotation checks the lengths of everything in a rotation, and that the pad will still have an owner.
+func checkRotation(data model.Rotation) (err error) {
+	// Check if the proof is a valid length, or there is a challenge response instead.
+	err = checkAuthLen(data.Pad)
+	if err != nil {
+		return
+	}
+
+	if data.NewProof == "" && data.NewPublicKey == "" {
+		return errNoNewProof
+	}
+
+	if data.NewProof != "" && len(data.NewProof) != model.ProofLen {
+		return errInvalidNewProofLen
+	}
+
+	if data.NewReadToken != "" && len(data.NewReadToken) != model.ProofLen {
+		return errInvalidReadTokenLen
+	}
+
+	if data.NewReadToken != "" && data.Public {
+		return errPublicReadToken
+	}
+
+	if data.NewPublicKey != "" {
+		_, err = decodePublicKey(data.NewPublicKey)
+		if err != nil {
+			return
+		}
+	}
+
+	if !model.ContentLen.Check(string(data.Content)) {
+		return errInvalidContentLen
+	}
+
+	err = checkEnvelope(data.Content)
+	if err != nil {
+		return
+	}
+
+	for _, content := range data.Revisions {
+		if !model.ContentLen.Check(string(content)) {
+			return errInvalidContentLen
+		}
+
+		err = checkEnvelope(content)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, attachment := range data.Attachments {
+		if int64(len(attachment)) > cfg.MaxAttachmentSize {
+			return errAttachmentTooLarge
+		}
+	}
+
+	return
+}
+
+// checkRotated checks a rotation re-encrypts exactly the revisions and attachments a pad has.
+// The store checks this again when rotating, in case any were added since.
+func checkRotated(id string, data model.Rotation) (err error) {
+	revisions, err := Store.Revisions(id)
+	if err != nil {
+		return
+	}
+
+	if len(revisions) != len(data.Revisions) {
+		return errIncompleteRotation
+	}
+
+	for _, revision := range revisions {
+		if _, ok := data.Revisions[revision.Revision]; !ok {
+			return errIncompleteRotation
+		}
+	}
+
+	attachments, err := Store.Attachments(id)
+	if err != nil {
+		return
+	}
+
+	if len(attachments) != len(data.Attachments) {
+		return errIncompleteRotation
+	}
+
+	for _, attachment := range attachments {
+		if _, ok := data.Attachments[attachment.ID]; !ok {
+			return errIncompleteRotation
+		}
+	}
+
+	return
+}
@@ -0,0 +1,132 @@
+package pad
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+)
+
+var (
+	errIncorrectSignature = errors.New("signature is incorrect")
+)
+
+// updateMessage is the message a client signs to update a pad from a revision.
+// It covers everything the update changes, so a relayed request can't be altered without breaking the signature,
+// and including the revision means a signature can't be replayed once the update is applied.
+func updateMessage(pad model.Pad, revision int64) []byte {
+	// The expiry the client sent is signed, which is only ExpiresAt if no TTL was sent.
+	expiresAt := pad.ExpiresAt
+	if pad.TTL != 0 {
+		expiresAt = 0
+	}
+
+	return []byte("cryptopad-update\n" + pad.ID + "\n" + hashHex([]byte(pad.Content)) + "\n" + strconv.FormatInt(revision, 10) + "\n" +
+		ownerFields(pad) + "\n" + strconv.FormatInt(pad.TTL, 10) + "\n" + strconv.FormatInt(expiresAt, 10) + "\n" +
+		strconv.FormatBool(pad.BurnAfterReading) + "\n" + strconv.FormatInt(pad.Compacted, 10))
+}
+
+// deleteMessage is the message a client signs to delete a pad at a revision.
+func deleteMessage(id string, revision int64) []byte {
+	return []byte("cryptopad-delete\n" + id + "\n" + strconv.FormatInt(revision, 10))
+}
+
+// rotateMessage is the message a client signs to rotate a pad's key at a revision.
+// It covers the re-encrypted content, history and attachments, the new keys, and whether the pad is made public,
+// for the same reasons as updates.
+func rotateMessage(rotation model.Rotation, revision int64) []byte {
+	// Revisions and attachments are hashed in order, so the message doesn't depend on how the maps are iterated.
+	revisions := make([]int64, 0, len(rotation.Revisions))
+	for rev := range rotation.Revisions {
+		revisions = append(revisions, rev)
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i] < revisions[j]
+	})
+
+	var history strings.Builder
+	for _, rev := range revisions {
+		history.WriteString(strconv.FormatInt(rev, 10) + " " + hashHex([]byte(rotation.Revisions[rev])) + "\n")
+	}
+
+	attachments := make([]string, 0, len(rotation.Attachments))
+	for attachment := range rotation.Attachments {
+		attachments = append(attachments, attachment)
+	}
+
+	sort.Strings(attachments)
+
+	var files strings.Builder
+	for _, attachment := range attachments {
+		files.WriteString(attachment + " " + hashHex(rotation.Attachments[attachment]) + "\n")
+	}
+
+	return []byte("cryptopad-rotate\n" + rotation.ID + "\n" + hashHex([]byte(rotation.Content)) + "\n" + strconv.FormatInt(revision, 10) + "\n" +
+		ownerFields(rotation.Pad) + "\n" + strconv.FormatInt(rotation.Compacted, 10) + "\n" +
+		hashHex([]byte(history.String())) + "\n" + hashHex([]byte(files.String())) + "\n" + strconv.FormatBool(rotation.Public))
+}
+
+// ownerFields are the new proof, public key and read token a signed change sets, on separate lines and empty if unchanged.
+func ownerFields(pad model.Pad) string {
+	return pad.NewProof + "\n" + pad.NewPublicKey + "\n" + pad.NewReadToken
+}
+
+// hashHex hashes data with SHA-256, encoded as hex.
+func hashHex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// opMessage is the message a client signs to append an op to a pad with a sequence.
+// Including the sequence means a signature can't be replayed once the op is appended.
+func opMessage(id string, op model.Ciphertext, sequence int64) []byte {
+	return []byte("cryptopad-op\n" + id + "\n" + hashHex([]byte(op)) + "\n" + strconv.FormatInt(sequence, 10))
+}
+
+// uploadMessage is the message a client signs to start uploading a pad's content in chunks, from a revision.
+// It covers the hash of every chunk, so whoever relays it can't upload chunks of their own.
+// Starting an upload doesn't change the pad's revision, so the signature is tied to the upload's ID too,
+// which can only be used once.
+func uploadMessage(id, upload string, chunks int, size int64, hashes string, revision int64) []byte {
+	return []byte("cryptopad-upload\n" + id + "\n" + upload + "\n" + strconv.Itoa(chunks) + "\n" + strconv.FormatInt(size, 10) + "\n" +
+		hashHex([]byte(hashes)) + "\n" + strconv.FormatInt(revision, 10))
+}
+
+// attachmentMessage is the message a client signs to attach a file to a pad with an ID.
+// Adding an attachment doesn't change the pad's revision, so the signature is tied to the attachment's ID instead,
+// which can only be used once, even if the attachment is removed.
+func attachmentMessage(id, attachment string, data []byte, revision int64) []byte {
+	return []byte("cryptopad-attachment\n" + id + "\n" + attachment + "\n" + hashHex(data) + "\n" + strconv.FormatInt(revision, 10))
+}
+
+// removeAttachmentMessage is the message a client signs to remove an attachment from a pad at a revision.
+func removeAttachmentMessage(id, attachment string, revision int64) []byte {
+	return []byte("cryptopad-remove-attachment\n" + id + "\n" + attachment + "\n" + strconv.FormatInt(revision, 10))
+}
+
+// verifySignature checks a base64 encoded signature of a message was made with a pad's key.
+func verifySignature(pad model.Pad, message []byte, encoded string) (err error) {
+	if pad.PublicKey == "" {
+		err = errNoPublicKey
+		return
+	}
+
+	key, err := decodePublicKey(pad.PublicKey)
+	if err != nil {
+		return
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || !ed25519.Verify(key, message, signature) {
+		err = errIncorrectSignature
+	}
+
+	return
+}
@@ -0,0 +1,327 @@
+package pad
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/VolticFroogo/config"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/db"
+)
+
+const (
+	// burntRetention is how long a record of a burnt pad is kept, so readers are told it's gone.
+	burntRetention = 24 * time.Hour
+)
+
+var (
+	errStaleRevision = errors.New("pad has been updated since the given revision")
+	errStaleSequence = errors.New("op log has been appended to since the given sequence")
+)
+
+// PadStore is a storage backend for pads.
+// Lookups of pads which don't exist return sql.ErrNoRows regardless of the backend.
+// Updates only apply if the pad is still at the given revision, otherwise errStaleRevision is returned.
+type PadStore interface {
+	FromID(id string) (model.Pad, error)
+	Insert(pad model.Pad) error
+	Update(pad model.Pad) error
+	Remove(id string) error
+
+	// Rotate atomically updates a pad along with the re-encrypted content of every revision and attachment it has.
+	// It also removes the pad's editors, ops and uploads, which were authorised by or encrypted with the old key.
+	// If an op was appended since the pad's compacted sequence errStaleSequence is returned,
+	// and if any revision or attachment isn't replaced errStaleRevision is returned.
+	Rotate(pad model.Pad, revisions map[int64]model.Ciphertext, attachments map[string][]byte) error
+
+	// Revisions and Revision read the history of ciphertexts kept by Insert and Update.
+	Revisions(id string) ([]model.Revision, error)
+	Revision(id string, revision int64) (model.Pad, error)
+
+	// RemoveExpired removes every pad which expired at or before a unix timestamp, returning their IDs.
+	RemoveExpired(now int64) ([]string, error)
+
+	// Usage counts the pads stored, and the bytes of their content (or chunks), attachments and uploads' staged chunks.
+	Usage() (model.Usage, error)
+
+	// Burn atomically reads and removes a pad, leaving a record that it was burnt.
+	// If the pad has already been burnt errBurnt is returned, so only one reader can get it.
+	Burn(id string) (model.Pad, error)
+	Burnt(id string) (bool, error)
+
+	// Editors are named proofs which can update a pad's content, and are removed with the pad.
+	Editors(id string) ([]model.Editor, error)
+	Editor(id, name string) (model.Editor, error)
+	PutEditor(id string, editor model.Editor) error
+	RemoveEditor(id, name string) error
+
+	// Lockouts count a pad's incorrect proofs in a row, so they survive restarts. Pads without any return sql.ErrNoRows.
+	// AddFailure atomically counts another, and LockPad locks the pad until a unix timestamp.
+	// Lockouts are removed with the pad.
+	Lockout(id string) (model.Lockout, error)
+	AddFailure(id string) (model.Lockout, error)
+	LockPad(id string, until int64) error
+	RemoveLockout(id string) error
+
+	// Ops are a pad's log of encrypted ops, which updates remove once they are compacted into the content.
+	// AppendOp only applies if the op's sequence directly follows the last, otherwise errStaleSequence is returned.
+	Ops(id string, since int64) ([]model.Op, error)
+	OpSequence(id string) (int64, error)
+	AppendOp(id string, op model.Op) error
+
+	// Uploads stage a pad's encrypted chunks until they have all been received.
+	// CommitUpload then atomically replaces the pad's content with them, if the pad is still at the upload's revision.
+	// Updates replace any chunks with their content, and removing a pad removes its chunks and uploads.
+	// Expired uploads lose their chunks, but are kept while the pad is still at their revision, so their IDs can't be reused.
+	// CreateUpload fails with errUploadExists if the upload's ID is taken, or errTooManyUploads if the pad already has
+	// max uploads which haven't expired (unless max is 0), checked in the same write.
+	// PutChunk fails with errPadTooLarge if the upload's chunks would add up to more than its size, checked in the same write,
+	// and otherwise returns how many more bytes are staged, which is negative if a larger chunk was replaced.
+	CreateUpload(upload model.Upload, max int) error
+	Upload(id string) (model.Upload, error)
+	PutChunk(upload string, index int, data []byte) (int64, error)
+	UploadChunks(upload string) ([]model.Chunk, error)
+	CommitUpload(upload model.Upload) error
+	Chunk(id string, index int) ([]byte, error)
+
+	// Attachments are encrypted files attached to a pad, which are removed with the pad.
+	// PutAttachment fails with errAttachmentExists if the pad already has an attachment with the ID,
+	// or had one which was removed while the pad was at its current revision,
+	// or errTooManyAttachments if it already has max attachments, checked in the same write.
+	Attachments(id string) ([]model.Attachment, error)
+	Attachment(id, attachment string) (model.Attachment, []byte, error)
+	PutAttachment(id string, attachment model.Attachment, data []byte, max int) error
+	RemoveAttachment(id, attachment string) error
+}
+
+// Config is the config structure.
+type Config struct {
+	// History is how many revisions of each pad to keep, including the current one.
+	History int
+
+	// SweepInterval is how often, in seconds, expired pads are removed. 0 disables sweeping.
+	SweepInterval int
+
+	// MaxOps is how many ops a pad can have waiting to be compacted. 0 is unlimited.
+	MaxOps int
+
+	// MaxChunkSize and MaxPadSize are the largest chunk, and total size of a pad's chunks, in bytes.
+	MaxChunkSize, MaxPadSize int64
+
+	// UploadLifetime is how long, in seconds, a client has to upload a pad's chunks and commit them.
+	UploadLifetime int64
+
+	// MaxUploads is how many uploads each pad can have open at once. 0 is unlimited.
+	MaxUploads int
+
+	// MaxAttachments is how many attachments a pad can have, and MaxAttachmentSize how large each can be in bytes.
+	MaxAttachments    int
+	MaxAttachmentSize int64
+
+	// RequireEnvelope rejects content which isn't in an envelope, rather than treating it as from an older client.
+	RequireEnvelope bool
+
+	// LockoutThreshold is how many incorrect proofs in a row lock a pad. 0 disables lockouts.
+	LockoutThreshold int
+
+	// LockoutDuration is how long, in seconds, a pad is first locked for, which doubles with each incorrect proof after,
+	// up to MaxLockoutDuration. A MaxLockoutDuration of 0 lets it keep doubling.
+	LockoutDuration, MaxLockoutDuration int64
+
+	// WorkDifficulty is how many leading zero bits the proof of work for creating a pad needs. 0 disables proof of work.
+	// A bit is added for every doubling of pads created in the last minute past WorkCreationRate, up to MaxWorkDifficulty.
+	WorkDifficulty, WorkCreationRate, MaxWorkDifficulty int
+
+	// MaxPads and MaxBytes are how many pads, and bytes of content, attachments and staged chunks, can be stored. 0 is unlimited.
+	// Nothing can be added once ReadOnlyAt (a fraction) of MaxBytes is used, though pads can still be read.
+	MaxPads, MaxBytes int64
+	ReadOnlyAt        float64
+
+	// MaxPadsPerIP is how many pads can be created from each address a day. 0 is unlimited.
+	MaxPadsPerIP int
+}
+
+var (
+	// Store is the pad store used by the handlers.
+	Store PadStore
+
+	cfg Config
+)
+
+// Init loads the config and selects the pad store matching the initialised database driver.
+func Init(configDirectory string) (err error) {
+	err = config.Load(configDirectory, &cfg)
+	if err != nil {
+		return
+	}
+
+	switch db.Driver {
+	case db.DriverMemory:
+		Store = NewMemoryStore(cfg.History)
+	case db.DriverSQLite:
+		// SQLite databases are embedded, so we are responsible for creating the tables.
+		store := NewSQLStore(db.SQL, db.Dot, cfg.History)
+		err = store.CreateTables()
+		Store = store
+	default:
+		Store = NewSQLStore(db.SQL, db.Dot, cfg.History)
+	}
+
+	// Any usage or creations counted were of the previous store.
+	forgetUsage()
+	forgetAddressCreations()
+	forgetCreations()
+	return
+}
+
+// FromID gets a pad from the store given an ID.
+// Expired pads which haven't been swept yet are removed, and treated as if they don't exist.
+func FromID(id string) (pad model.Pad, err error) {
+	pad, err = Store.FromID(id)
+	if err != nil {
+		return
+	}
+
+	if pad.ExpiresAt != 0 && pad.ExpiresAt <= time.Now().Unix() {
+		err = Remove(id)
+		if err != nil {
+			return
+		}
+
+		pad = model.Pad{}
+		err = sql.ErrNoRows
+	}
+
+	return
+}
+
+// Insert a pad into the store, hashing its new proof and registering its new public key.
+// Pads owned by a public key may have no proof, which is stored as empty.
+func Insert(pad model.Pad) (err error) {
+	pad.PublicKey = pad.NewPublicKey
+	pad.Compacted = 0
+	pad.Chunks = 0
+	pad.Size = int64(len(pad.Content))
+
+	if pad.NewReadToken != "" {
+		pad.ReadToken = hashReadToken(pad.NewReadToken)
+	}
+
+	if pad.NewProof != "" {
+		pad.NewProof, err = HashProof(pad.NewProof)
+		if err != nil {
+			return
+		}
+	}
+
+	err = Store.Insert(pad)
+	if err != nil {
+		return
+	}
+
+	addUsage(1, pad.Size)
+	publishUpdate(pad, 1)
+	return
+}
+
+// Update a pad in the store, hashing its new proof.
+// If there is no new proof, the pad's proof is taken to already be the stored hash.
+// Otherwise, as updates rewrite the proof, this also upgrades legacy plaintext proofs.
+// The content replaces any chunks the pad had.
+// Changing the read token closes the pad's subscribers, as they may have had the old one.
+func Update(pad model.Pad) (err error) {
+	pad.Chunks = 0
+	pad.Size = int64(len(pad.Content))
+
+	if pad.NewProof == "" {
+		pad.NewProof = pad.Proof
+	} else {
+		pad.NewProof, err = HashProof(pad.NewProof)
+		if err != nil {
+			return
+		}
+	}
+
+	err = Store.Update(pad)
+	if err != nil {
+		return
+	}
+
+	if pad.NewReadToken != "" {
+		publishRevoke(pad.ID, pad.Revision+1)
+		return
+	}
+
+	publishUpdate(pad, pad.Revision+1)
+	return
+}
+
+// Rotate a pad in the store to a new key, hashing its new proof.
+// Its subscribers are closed and its challenges forgotten, as they were authorised by the old key.
+// Its uploads are removed too, so the usage is counted again.
+func Rotate(pad model.Pad, revisions map[int64]model.Ciphertext, attachments map[string][]byte) (err error) {
+	pad.Chunks = 0
+	pad.Size = int64(len(pad.Content))
+
+	if pad.NewProof != "" {
+		pad.NewProof, err = HashProof(pad.NewProof)
+		if err != nil {
+			return
+		}
+	}
+
+	err = Store.Rotate(pad, revisions, attachments)
+	if err != nil {
+		return
+	}
+
+	forgetUsage()
+	forgetChallenges(pad.ID)
+	publishRotate(pad.ID, pad.Revision+1)
+	return
+}
+
+// Remove a pad from the store, closing its subscribers.
+func Remove(id string) (err error) {
+	err = Store.Remove(id)
+	if err != nil {
+		return
+	}
+
+	forgetUsage()
+	publishDelete(id)
+	return
+}
+
+// Sweep removes expired pads from the store every sweep interval, forever.
+func Sweep() {
+	if cfg.SweepInterval <= 0 {
+		return
+	}
+
+	for range time.Tick(time.Duration(cfg.SweepInterval) * time.Second) {
+		err := RemoveExpired(time.Now().Unix())
+		if err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// RemoveExpired removes every pad which expired at or before a unix timestamp from the store, closing their subscribers.
+func RemoveExpired(now int64) (err error) {
+	removed, err := Store.RemoveExpired(now)
+	if err != nil {
+		return
+	}
+
+	// Expired uploads are removed too, so the usage changes even if no pads expired.
+	forgetUsage()
+
+	for _, id := range removed {
+		publishDelete(id)
+	}
+
+	return
+}
@@ -0,0 +1,296 @@
+package pad
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// EventUpdated is published when a pad is created or its content changes.
+	EventUpdated = "updated"
+
+	// EventDeleted is published when a pad is removed, after which its subscribers are closed.
+	EventDeleted = "deleted"
+
+	// EventRotated is published when a pad's key is rotated, after which its subscribers are closed.
+	// Subscribers must get the pad again, as they may no longer be allowed to read it.
+	EventRotated = "rotated"
+
+	// EventRevoked is published when a pad's read token is changed, after which its subscribers are closed.
+	// Subscribers with the new read token must get the pad and subscribe again.
+	EventRevoked = "revoked"
+
+	// subscriberBuffer is how many events can wait for a subscriber before it is dropped as too slow.
+	subscriberBuffer = 16
+
+	// pingInterval is how often subscribers are pinged, to keep connections through proxies alive.
+	pingInterval = 30 * time.Second
+
+	// writeTimeout is how long writing an event to a subscriber may take.
+	writeTimeout = 10 * time.Second
+)
+
+var (
+	errBurnSubscribe = errors.New("burn after reading pads can not be subscribed to")
+)
+
+// subscriber is a client receiving the events of a pad.
+type subscriber struct {
+	events chan model.Event
+}
+
+var (
+	subscribersMutex sync.Mutex
+	subscribers      = make(map[string]map[*subscriber]struct{})
+
+	upgrader = websocket.Upgrader{}
+)
+
+// subscribe starts receiving the events of a pad.
+func subscribe(id string) *subscriber {
+	sub := &subscriber{
+		events: make(chan model.Event, subscriberBuffer),
+	}
+
+	subscribersMutex.Lock()
+	defer subscribersMutex.Unlock()
+
+	if subscribers[id] == nil {
+		subscribers[id] = make(map[*subscriber]struct{})
+	}
+
+	subscribers[id][sub] = struct{}{}
+	return sub
+}
+
+// unsubscribe stops a subscriber receiving events, closing its channel if it hasn't been already.
+func unsubscribe(id string, sub *subscriber) {
+	subscribersMutex.Lock()
+	defer subscribersMutex.Unlock()
+
+	unsubscribeLocked(id, sub)
+}
+
+// unsubscribeLocked is unsubscribe, but the subscribers mutex must already be locked.
+func unsubscribeLocked(id string, sub *subscriber) {
+	if _, ok := subscribers[id][sub]; !ok {
+		return
+	}
+
+	delete(subscribers[id], sub)
+	if len(subscribers[id]) == 0 {
+		delete(subscribers, id)
+	}
+
+	close(sub.events)
+}
+
+// CloseSubscribers closes every subscriber of every pad, so their connections end when the server shuts down.
+func CloseSubscribers() {
+	subscribersMutex.Lock()
+	defer subscribersMutex.Unlock()
+
+	for id, subs := range subscribers {
+		for sub := range subs {
+			unsubscribeLocked(id, sub)
+		}
+	}
+}
+
+// publish sends an event to every subscriber of a pad.
+// Subscribers which have fallen too far behind are dropped rather than holding up the update.
+// Once a pad is deleted, rotated or its read token changed its subscribers are closed,
+// as there will be nothing more they can be sent.
+func publish(event model.Event) {
+	subscribersMutex.Lock()
+	defer subscribersMutex.Unlock()
+
+	for sub := range subscribers[event.ID] {
+		select {
+		case sub.events <- event:
+		default:
+			unsubscribeLocked(event.ID, sub)
+		}
+	}
+
+	if event.Type == EventDeleted || event.Type == EventRotated || event.Type == EventRevoked {
+		for sub := range subscribers[event.ID] {
+			unsubscribeLocked(event.ID, sub)
+		}
+	}
+}
+
+// publishUpdate publishes the new content and revision of a pad.
+// The content of burn after reading pads is left out, so it can only be read by burning the pad.
+func publishUpdate(pad model.Pad, revision int64) {
+	event := model.Event{
+		Type:     EventUpdated,
+		ID:       pad.ID,
+		Revision: revision,
+		Content:  pad.Content,
+		Chunks:   pad.Chunks,
+	}
+
+	if pad.BurnAfterReading {
+		event.Content = ""
+	}
+
+	publish(event)
+}
+
+// publishDelete publishes that a pad has been removed.
+func publishDelete(id string) {
+	publish(model.Event{
+		Type: EventDeleted,
+		ID:   id,
+	})
+}
+
+// publishRotate publishes that a pad's key has been rotated at a revision.
+func publishRotate(id string, revision int64) {
+	publish(model.Event{
+		Type:     EventRotated,
+		ID:       id,
+		Revision: revision,
+	})
+}
+
+// publishRevoke publishes that a pad's read token has been changed at a revision.
+// The new content isn't sent, as subscribers may have had the old read token.
+func publishRevoke(id string, revision int64) {
+	publish(model.Event{
+		Type:     EventRevoked,
+		ID:       id,
+		Revision: revision,
+	})
+}
+
+// Subscribe upgrades a request to a WebSocket which receives a pad's events.
+// Events are sent as JSON text messages, or binary messages if the request accepts CBOR or MessagePack.
+func Subscribe(w http.ResponseWriter, r *http.Request) {
+	pad, err := subscribable(r)
+	if err != nil {
+		helper.ThrowErr(err, subscribeErrStatus(err), w)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied to the client.
+		return
+	}
+
+	defer conn.Close()
+
+	// The negotiated format is kept, as the connection no longer has a response to encode into.
+	format := helper.ResponseFormat(w)
+
+	sub := subscribe(pad.ID)
+	defer unsubscribe(pad.ID, sub)
+
+	// Clients don't send anything, but reading notices when they disconnect.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+
+		for {
+			_, _, err := conn.NextReader()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.events:
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+			if !ok {
+				// The pad was deleted or we fell behind, either way there is nothing more to send.
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+
+			err = writeMessage(conn, format, event)
+			if err != nil {
+				log.Print(err)
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+			err = conn.WriteMessage(websocket.PingMessage, nil)
+			if err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// writeMessage writes an event to a WebSocket in a format.
+func writeMessage(conn *websocket.Conn, format string, event model.Event) error {
+	data, err := helper.Marshal(format, event)
+	if err != nil {
+		return err
+	}
+
+	if format == helper.FormatJSON {
+		return conn.WriteMessage(websocket.TextMessage, data)
+	}
+
+	return conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// subscribable gets the pad a subscription request is for, checking it may be subscribed to.
+func subscribable(r *http.Request) (pad model.Pad, err error) {
+	id := mux.Vars(r)["id"]
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(id) {
+		err = errInvalidIDLen
+		return
+	}
+
+	pad, err = FromID(id)
+	if err != nil {
+		return
+	}
+
+	// Otherwise the content could be read without burning the pad.
+	if pad.BurnAfterReading {
+		err = errBurnSubscribe
+		return
+	}
+
+	err = authoriseRead(pad, r)
+	return
+}
+
+// subscribeErrStatus gets the status code for an error from subscribing to a pad.
+func subscribeErrStatus(err error) int {
+	switch err {
+	case errInvalidIDLen:
+		return http.StatusBadRequest
+	case sql.ErrNoRows:
+		return http.StatusNotFound
+	case errBurnSubscribe, errIncorrectReadToken:
+		return http.StatusForbidden
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,442 @@
+package pad
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+	"github.com/gorilla/mux"
+)
+
+const (
+	uploadIDLen = 32
+)
+
+var (
+	errInvalidUpload    = errors.New("uploads must have at least one chunk, and no more chunks than bytes")
+	errPadTooLarge      = errors.New("pad is larger than the maximum size")
+	errChunkTooLarge    = errors.New("chunk is larger than the maximum size")
+	errInvalidChunk     = errors.New("chunk must be between 0 and the number of chunks in the upload")
+	errEmptyChunk       = errors.New("chunks can not be empty")
+	errUnknownUpload    = errors.New("upload is unknown, expired or already committed")
+	errIncompleteUpload = errors.New("upload is missing chunks, or they don't add up to its size")
+	errBurnChunks       = errors.New("burn after reading pads can not be uploaded in chunks")
+	errTooManyUploads   = errors.New("pad already has the maximum number of open uploads")
+	errUploadExists     = errors.New("an upload with the id already exists")
+	errInvalidUploadID  = fmt.Errorf("upload ids must be %v base64url encoded bytes", uploadIDLen)
+	errNoUploadID       = errors.New("signed uploads must choose their id in upload")
+	errInvalidHashes    = errors.New("hashes must be the hex sha-256 hash of each chunk, in order")
+	errNoHashes         = errors.New("signed uploads must send the hash of each chunk")
+	errChunkMismatch    = errors.New("chunk doesn't match the hash the upload was started with")
+)
+
+// StartUpload starts replacing a pad's content with chunks, which the owner or an editor must authorise.
+// The response's ID authorises uploading the chunks, so they can be sent (and resent) without the proof.
+func StartUpload(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(id) {
+		helper.ThrowErr(errInvalidIDLen, http.StatusBadRequest, w)
+		return
+	}
+
+	// Get data from the request, in whichever format it was sent.
+	var data model.Pad
+	err := decode(w, r, &data)
+	if err != nil {
+		helper.ThrowErr(err, helper.DecodeErrStatus(err), w)
+		return
+	}
+
+	if data.Chunks < 1 || int64(data.Chunks) > data.Size {
+		helper.ThrowErr(errInvalidUpload, http.StatusBadRequest, w)
+		return
+	}
+
+	if data.Size > cfg.MaxPadSize || data.Size > int64(data.Chunks)*cfg.MaxChunkSize {
+		helper.ThrowErr(errPadTooLarge, http.StatusRequestEntityTooLarge, w)
+		return
+	}
+
+	// Check if the proof is a valid length, or there is a challenge response instead.
+	err = checkAuthLen(data)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
+		return
+	}
+
+	pad, err := FromID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	// Otherwise the chunks could be read without burning the pad.
+	if pad.BurnAfterReading {
+		helper.ThrowErr(errBurnChunks, http.StatusForbidden, w)
+		return
+	}
+
+	// Clients can choose the upload's ID, and must when signing, so a signature can only start one upload.
+	uploadID, err := chosenUploadID(data.Upload, data.Signature != "")
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
+		return
+	}
+
+	// Signatures cover the hash of each chunk, so only the chunks which were signed can be uploaded.
+	err = checkHashes(data.Hashes, data.Chunks, data.Signature != "")
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
+		return
+	}
+
+	// Both the owner and editors can upload chunks, as they only change the content.
+	_, err = authorise(pad, data, uploadMessage(id, uploadID, data.Chunks, data.Size, data.Hashes, pad.Revision))
+	if err != nil {
+		throwAuthErr(err, w)
+		return
+	}
+
+	// Nothing can be added while the server is nearly full.
+	err = checkCapacity()
+	if err != nil {
+		helper.ThrowErr(err, quotaErrStatus(err), w)
+		return
+	}
+
+	upload := model.Upload{
+		ID:        uploadID,
+		Pad:       id,
+		Revision:  pad.Revision,
+		Chunks:    data.Chunks,
+		Size:      data.Size,
+		ExpiresAt: time.Now().Unix() + cfg.UploadLifetime,
+		Hashes:    data.Hashes,
+	}
+
+	err = Store.CreateUpload(upload, cfg.MaxUploads)
+	if err != nil {
+		if err == errTooManyUploads || err == errUploadExists {
+			helper.ThrowErr(err, http.StatusConflict, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	helper.Response(upload, http.StatusCreated, w)
+}
+
+// GetUpload gets an upload and the chunks it has received, so an interrupted upload can be resumed.
+func GetUpload(w http.ResponseWriter, r *http.Request) {
+	upload, err := uploadFromRequest(r)
+	if err != nil {
+		helper.ThrowErr(err, uploadErrStatus(err), w)
+		return
+	}
+
+	upload.Received, err = Store.UploadChunks(upload.ID)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	helper.Response(upload, http.StatusOK, w)
+}
+
+// PutChunk uploads an encrypted chunk as the raw request body, replacing it if it was already uploaded.
+func PutChunk(w http.ResponseWriter, r *http.Request) {
+	upload, err := uploadFromRequest(r)
+	if err != nil {
+		helper.ThrowErr(err, uploadErrStatus(err), w)
+		return
+	}
+
+	index, err := chunkIndex(r, upload.Chunks)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
+		return
+	}
+
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, cfg.MaxChunkSize))
+	if err != nil {
+		helper.ThrowErr(errChunkTooLarge, http.StatusRequestEntityTooLarge, w)
+		return
+	}
+
+	if len(data) == 0 {
+		helper.ThrowErr(errEmptyChunk, http.StatusBadRequest, w)
+		return
+	}
+
+	if hashes := chunkHashes(upload); hashes != nil && hashHex(data) != hashes[index] {
+		helper.ThrowErr(errChunkMismatch, http.StatusBadRequest, w)
+		return
+	}
+
+	// Staged chunks count towards the store's usage, so nothing can be staged while it is nearly full.
+	err = checkCapacity()
+	if err != nil {
+		helper.ThrowErr(err, quotaErrStatus(err), w)
+		return
+	}
+
+	// The store checks the chunks don't add up to more than the upload's size.
+	// A chunk which was resent replaces the one staged before.
+	staged, err := Store.PutChunk(upload.ID, index, data)
+	if err != nil {
+		if err == errPadTooLarge {
+			helper.ThrowErr(err, http.StatusRequestEntityTooLarge, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	addUsage(0, staged)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// CommitUpload replaces a pad's content with an upload's chunks, once they have all been received.
+func CommitUpload(w http.ResponseWriter, r *http.Request) {
+	upload, err := uploadFromRequest(r)
+	if err != nil {
+		helper.ThrowErr(err, uploadErrStatus(err), w)
+		return
+	}
+
+	received, err := Store.UploadChunks(upload.ID)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	// Chunks are listed in order, so every chunk has been received if each index is where it should be.
+	hashes := chunkHashes(upload)
+
+	var size int64
+	for i, chunk := range received {
+		if chunk.Index != i {
+			break
+		}
+
+		// Chunks are checked against the hashes as they are uploaded, and again here so only signed chunks are ever committed.
+		if hashes != nil && chunk.Hash != hashes[i] {
+			helper.ThrowErr(errChunkMismatch, http.StatusBadRequest, w)
+			return
+		}
+
+		size += chunk.Size
+	}
+
+	if len(received) != upload.Chunks || size != upload.Size {
+		helper.ThrowErr(errIncompleteUpload, http.StatusBadRequest, w)
+		return
+	}
+
+	// The chunks stop being staged and become the content, so only the content they replace is freed.
+	pad, err := Store.FromID(upload.Pad)
+	if err != nil && err != sql.ErrNoRows {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	err = Store.CommitUpload(upload)
+	if err != nil {
+		// The pad was updated (or removed) since the upload started.
+		if err == errStaleRevision {
+			helper.ThrowErr(err, http.StatusConflict, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	addUsage(0, -pad.Size)
+
+	publishUpdate(model.Pad{
+		ID:     upload.Pad,
+		Chunks: upload.Chunks,
+		Size:   upload.Size,
+	}, upload.Revision+1)
+
+	w.Header().Set("ETag", etag(upload.Revision+1))
+	w.WriteHeader(http.StatusOK)
+}
+
+// Chunk downloads an encrypted chunk of a pad's content as raw bytes.
+// Clients can send the pad's revision in If-Match, so the chunks they download are all from the same upload.
+func Chunk(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// Check if the ID is a valid length.
+	if !model.IDLen.Check(id) {
+		helper.ThrowErr(errInvalidIDLen, http.StatusBadRequest, w)
+		return
+	}
+
+	pad, err := FromID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	if pad.BurnAfterReading {
+		helper.ThrowErr(errBurnChunks, http.StatusForbidden, w)
+		return
+	}
+
+	err = authoriseRead(pad, r)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusForbidden, w)
+		return
+	}
+
+	w.Header().Set("ETag", etag(pad.Revision))
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch != "" && !matchesRevision(ifMatch, pad.Revision) {
+		helper.ThrowErr(errRevisionMismatch, http.StatusPreconditionFailed, w)
+		return
+	}
+
+	index, err := chunkIndex(r, pad.Chunks)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusBadRequest, w)
+		return
+	}
+
+	data, err := Store.Chunk(id, index)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helper.ThrowErr(err, http.StatusNotFound, w)
+			return
+		}
+
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// chosenUploadID checks an upload ID chosen by the client, or generates one if it didn't choose one and isn't signing.
+func chosenUploadID(chosen string, signed bool) (id string, err error) {
+	if chosen != "" {
+		decoded, decodeErr := base64.RawURLEncoding.DecodeString(chosen)
+		if decodeErr != nil || len(decoded) != uploadIDLen {
+			err = errInvalidUploadID
+			return
+		}
+
+		id = chosen
+		return
+	}
+
+	if signed {
+		err = errNoUploadID
+		return
+	}
+
+	token := make([]byte, uploadIDLen)
+	_, err = rand.Read(token)
+	if err != nil {
+		return
+	}
+
+	id = base64.RawURLEncoding.EncodeToString(token)
+	return
+}
+
+// checkHashes checks the chunk hashes an upload is started with, which are required when signing.
+func checkHashes(hashes string, chunks int, signed bool) error {
+	if hashes == "" {
+		if signed {
+			return errNoHashes
+		}
+
+		return nil
+	}
+
+	split := strings.Split(hashes, ",")
+	if len(split) != chunks {
+		return errInvalidHashes
+	}
+
+	for _, hash := range split {
+		decoded, err := hex.DecodeString(hash)
+		if err != nil || len(decoded) != sha256.Size || hash != hex.EncodeToString(decoded) {
+			return errInvalidHashes
+		}
+	}
+
+	return nil
+}
+
+// chunkHashes gets the hash each of an upload's chunks must have, or nil if it was started without them.
+func chunkHashes(upload model.Upload) []string {
+	if upload.Hashes == "" {
+		return nil
+	}
+
+	return strings.Split(upload.Hashes, ",")
+}
+
+// uploadFromRequest gets the upload a request's URL is for, treating expired uploads as unknown.
+func uploadFromRequest(r *http.Request) (upload model.Upload, err error) {
+	upload, err = Store.Upload(mux.Vars(r)["upload"])
+	if err == sql.ErrNoRows || err == nil && upload.ExpiresAt <= time.Now().Unix() {
+		upload = model.Upload{}
+		err = errUnknownUpload
+	}
+
+	return
+}
+
+// chunkIndex gets and validates the chunk index from a request's URL.
+func chunkIndex(r *http.Request, chunks int) (index int, err error) {
+	index, err = strconv.Atoi(mux.Vars(r)["index"])
+	if err != nil || index < 0 || index >= chunks {
+		err = errInvalidChunk
+	}
+
+	return
+}
+
+// uploadErrStatus gets the status code for an error from getting an upload.
+func uploadErrStatus(err error) int {
+	if err == errUnknownUpload {
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,166 @@
+package pad
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+)
+
+const (
+	// workLifetime is how long a client has to solve a proof of work challenge and create its pad.
+	workLifetime = 5 * time.Minute
+
+	// creationWindow is how far back pad creations are counted, when scaling the difficulty of proof of work.
+	creationWindow = time.Minute
+
+	workLen = 32
+)
+
+var (
+	errNoWork      = errors.New("new pads need a solved proof of work challenge")
+	errInvalidWork = errors.New("proof of work challenge is unknown, expired, already used or unsolved")
+)
+
+var (
+	workMutex sync.Mutex
+	works     = make(map[string]model.Work)
+
+	// creations are when pads were created within the creation window, oldest first.
+	creations []time.Time
+)
+
+// Work issues a single use proof of work challenge, which must be solved to create a new pad.
+// Servers which don't require proof of work send a difficulty of 0, and no challenge.
+func Work(w http.ResponseWriter, r *http.Request) {
+	difficulty := workDifficulty()
+	if difficulty == 0 {
+		helper.Response(model.Work{}, http.StatusOK, w)
+		return
+	}
+
+	nonce := make([]byte, workLen)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		helper.ThrowErr(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	work := model.Work{
+		Challenge:  base64.RawURLEncoding.EncodeToString(nonce),
+		Difficulty: difficulty,
+		ExpiresAt:  time.Now().Add(workLifetime).Unix(),
+	}
+
+	workMutex.Lock()
+
+	// Forget any challenges which were never solved.
+	for key, other := range works {
+		if time.Now().Unix() > other.ExpiresAt {
+			delete(works, key)
+		}
+	}
+
+	works[work.Challenge] = work
+
+	workMutex.Unlock()
+
+	helper.Response(work, http.StatusOK, w)
+}
+
+// checkWork checks a new pad was sent with a solved proof of work challenge, if the server requires one.
+// Challenges are used up by any solution, right or wrong, so each can only create one pad.
+func checkWork(data model.Pad) error {
+	if cfg.WorkDifficulty <= 0 {
+		return nil
+	}
+
+	if data.Work == "" {
+		return errNoWork
+	}
+
+	workMutex.Lock()
+	work, ok := works[data.Work]
+	delete(works, data.Work)
+	workMutex.Unlock()
+
+	if !ok || time.Now().Unix() > work.ExpiresAt || !work.Solves(data.Nonce) {
+		return errInvalidWork
+	}
+
+	return nil
+}
+
+// workErrStatus gets the status code for an error from checking proof of work.
+func workErrStatus(err error) int {
+	if err == errNoWork {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusForbidden
+}
+
+// workDifficulty gets the difficulty of new proof of work challenges, or 0 if proof of work isn't required.
+// A bit is added to WorkDifficulty for every doubling of the pads created in the last minute past WorkCreationRate,
+// so spamming pads gets harder the more it is done.
+func workDifficulty() int {
+	if cfg.WorkDifficulty <= 0 {
+		return 0
+	}
+
+	workMutex.Lock()
+	created := len(recentCreations())
+	workMutex.Unlock()
+
+	difficulty := cfg.WorkDifficulty
+	if cfg.WorkCreationRate > 0 {
+		for rate := cfg.WorkCreationRate; created >= rate; rate *= 2 {
+			difficulty++
+		}
+	}
+
+	if cfg.MaxWorkDifficulty > 0 && difficulty > cfg.MaxWorkDifficulty {
+		difficulty = cfg.MaxWorkDifficulty
+	}
+
+	return difficulty
+}
+
+// recordCreation counts a pad being created, to scale the difficulty of proof of work.
+func recordCreation() {
+	if cfg.WorkDifficulty <= 0 {
+		return
+	}
+
+	workMutex.Lock()
+	defer workMutex.Unlock()
+
+	creations = append(recentCreations(), time.Now())
+}
+
+// forgetCreations forgets when pads were created, so the difficulty of proof of work starts again.
+func forgetCreations() {
+	workMutex.Lock()
+	defer workMutex.Unlock()
+
+	creations = nil
+}
+
+// recentCreations forgets creations from before the creation window, and gets the rest.
+// The mutex must already be locked.
+func recentCreations() []time.Time {
+	since := time.Now().Add(-creationWindow)
+
+	old := 0
+	for old < len(creations) && creations[old].Before(since) {
+		old++
+	}
+
+	creations = creations[old:]
+	return creations
+}
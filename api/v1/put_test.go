@@ -3,6 +3,7 @@ package v1
 import (
 	"database/sql"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
@@ -44,7 +45,7 @@ func putNew(t *testing.T, client *http.Client) {
 		t.Error(err.Error())
 	}
 
-	if expected != output {
+	if !matches(expected, output) {
 		t.Errorf("put new: output differs from expected while putting new pad (%v, %v)", res.Status, errorResponse.Error)
 		return
 	}
@@ -99,7 +100,7 @@ func putUpdate(t *testing.T, client *http.Client) {
 		t.Error(err.Error())
 	}
 
-	if expected != output {
+	if !matches(expected, output) {
 		t.Errorf("put update: output differs from expected while putting updated pad (%v, %v)", res.Status, errorResponse.Error)
 		return
 	}
@@ -153,7 +154,7 @@ func putIncorrectProof(t *testing.T, client *http.Client) {
 		t.Error(err.Error())
 	}
 
-	if expected != output {
+	if !matches(expected, output) {
 		t.Errorf("put incorrect proof: pad still got updated with invalid incorrect proof (%v, %v)", res.Status, errorResponse.Error)
 		return
 	}
@@ -207,7 +208,7 @@ func putInvalidProofLen(t *testing.T, client *http.Client) {
 		t.Error(err.Error())
 	}
 
-	if expected != output {
+	if !matches(expected, output) {
 		t.Errorf("put invalid proof len: pad still got updated with invalid proof len (%v, %v)", res.Status, errorResponse.Error)
 		return
 	}
@@ -361,3 +362,216 @@ func putIDTooLong(t *testing.T, client *http.Client) {
 
 	t.Logf("put id too long: success (%v, %v)", res.Status, errorResponse.Error)
 }
+
+// putLegacyProof tests that a pad with a plaintext proof can be updated, and has its proof hashed.
+func putLegacyProof(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "legacy-proof",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	updated := model.Pad{
+		ID:      "legacy-proof",
+		Content: "OTHER-ENCRYPTED-STUFF-HERE",
+		Proof:   "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	// Insert straight into the store to skip hashing, as pads did before proofs were hashed.
+	err = pad.Store.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	res, err, errorResponse := request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("put legacy proof: could not update pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	output, err := pad.FromID(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	if output.Proof == original.NewProof || !pad.VerifyProof(output.Proof, updated.Proof) {
+		t.Errorf("put legacy proof: proof was not hashed after update (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("put legacy proof: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// putIfMatch tests an update which is conditional on the current revision.
+func putIfMatch(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "if-match",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	updated := model.Pad{
+		ID:      "if-match",
+		Content: "OTHER-ENCRYPTED-STUFF-HERE",
+		Proof:   "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	header := http.Header{}
+	header.Set("If-Match", `"1"`)
+
+	res, err, errorResponse := requestWithHeader(t, client, updated, nil, http.MethodPut, baseURL+"pad", header)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("put if match: could not update pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	if res.Header.Get("ETag") != `"2"` {
+		t.Errorf("put if match: expected etag of revision 2, got %v (%v, %v)", res.Header.Get("ETag"), res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("put if match: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// putStaleIfMatch tests an update which is conditional on an old revision.
+func putStaleIfMatch(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "stale-if-match",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	updated := model.Pad{
+		ID:      "stale-if-match",
+		Content: "OTHER-ENCRYPTED-STUFF-HERE",
+		Proof:   "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	// Move the pad on to revision 2, so revision 1 is stale.
+	res, err, errorResponse := request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	header := http.Header{}
+	header.Set("If-Match", `"1"`)
+
+	res, err, errorResponse = requestWithHeader(t, client, updated, nil, http.MethodPut, baseURL+"pad", header)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("put stale if match: expected status precondition failed (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	if res.Header.Get("ETag") != `"2"` {
+		t.Errorf("put stale if match: expected etag of current revision 2, got %v (%v, %v)", res.Header.Get("ETag"), res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("put stale if match: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// putEscaped tests the longest content is accepted even when JSON escaping makes it six times as long.
+func putEscaped(t *testing.T, client *http.Client) {
+	body := model.Pad{
+		ID:       "escaped-pad",
+		Content:  model.Ciphertext(strings.Repeat("\x01", model.ContentLen.Max)),
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(body.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	res, err, errorResponse := request(t, client, body, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("put escaped: could not put the longest content (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	err = pad.Remove(body.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	t.Logf("put escaped: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// putTooLarge tests a request with a body larger than any pad could be is refused, without it being read.
+func putTooLarge(t *testing.T, client *http.Client) {
+	body := model.Pad{
+		ID:       "too-large",
+		Content:  model.Ciphertext(strings.Repeat("A", 7*model.ContentLen.Max)),
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(body.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	res, err, errorResponse := request(t, client, body, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("put too large: expected status request entity too large (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	_, err = pad.FromID(body.ID)
+	if err != sql.ErrNoRows {
+		t.Errorf("put too large: pad was created (%v)", err)
+		return
+	}
+
+	t.Logf("put too large: success (%v, %v)", res.Status, errorResponse.Error)
+}
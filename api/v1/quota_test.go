@@ -0,0 +1,197 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+)
+
+const (
+	quotaPadCfgDir = "../../configs/pad_quota_test.ini"
+)
+
+// quota tests each address can only create so many pads a day, and that the server is read only once it is nearly full.
+func quota(t *testing.T, client *http.Client) {
+	// The other tests don't have quotas, so remove them again afterwards.
+	err := pad.Init(quotaPadCfgDir)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	defer pad.Init(padCfgDir)
+
+	for _, id := range []string{"quota-pad-0", "quota-pad-1", "quota-pad-2", "quota-large"} {
+		err = pad.Remove(id)
+		if err != nil {
+			t.Error(err.Error())
+		}
+	}
+
+	created := model.Pad{
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	// The quota config allows 2 pads to be created from each address a day.
+	for i, status := range []int{http.StatusCreated, http.StatusCreated, http.StatusTooManyRequests} {
+		created.ID = "quota-pad-" + strconv.Itoa(i)
+
+		res, err, errorResponse := request(t, client, created, nil, http.MethodPut, baseURL+"pad")
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != status {
+			t.Errorf("quota: creating pad %v was %v rather than %v (%v)", i, res.Status, status, errorResponse.Error)
+			return
+		}
+	}
+
+	// The quota config is read only once half of its 1 MiB is used.
+	large := model.Pad{
+		ID:       "quota-large",
+		Content:  model.Ciphertext(strings.Repeat("A", 600*1024)),
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err = pad.Insert(large)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	res, err, errorResponse := request(t, client, created, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusInsufficientStorage {
+		t.Errorf("quota: pad was created while nearly full (%v, %v)", res.Status, errorResponse.Error)
+	}
+
+	res, err, errorResponse = getRequest(t, client, nil, baseURL+"pad/quota-pad-0")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("quota: pad couldn't be read while nearly full (%v, %v)", res.Status, errorResponse.Error)
+	}
+
+	err = pad.Remove(large.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+// quotaConcurrent tests pads created at once from an address can't go over its quota,
+// and pads which fail to be created aren't counted against it.
+func quotaConcurrent(t *testing.T, client *http.Client) {
+	err := pad.Init(quotaPadCfgDir)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	defer pad.Init(padCfgDir)
+
+	created := model.Pad{
+		ID:       "quota-reserved",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err = pad.Remove(created.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	reservation := &model.Reservation{}
+
+	res, err, errorResponse := request(t, client, model.Reservation{ID: created.ID}, reservation, http.MethodPost, baseURL+"reservation")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("quota concurrent: could not reserve pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	// Creating a pad reserved by another client fails, after the quota was checked.
+	res, err, errorResponse = request(t, client, created, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusConflict {
+		t.Errorf("quota concurrent: reserved pad was created (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		count int
+	)
+
+	for i := 0; i < 8; i++ {
+		body := created
+		body.ID = "quota-race-" + strconv.Itoa(i)
+
+		err = pad.Remove(body.ID)
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			res, err, _ := request(t, client, body, nil, http.MethodPut, baseURL+"pad")
+			if err == nil && res.StatusCode == http.StatusCreated {
+				mutex.Lock()
+				count++
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// The quota config allows 2 pads to be created from each address a day.
+	if count != 2 {
+		t.Errorf("quota concurrent: %v pads were created rather than 2", count)
+	}
+
+	// Use up the reservation without a quota, so it doesn't outlast the test.
+	err = pad.Init(padCfgDir)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	created.Reservation = reservation.Token
+
+	res, err, errorResponse = request(t, client, created, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("quota concurrent: could not create reserved pad (%v, %v)", res.Status, errorResponse.Error)
+	}
+}
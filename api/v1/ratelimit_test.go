@@ -0,0 +1,106 @@
+package v1
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+	"github.com/VolticFroogo/cryptopad-server/ratelimit"
+)
+
+// rateLimit tests requests to a pad, and failed proofs, are limited.
+func rateLimit(t *testing.T, client *http.Client) {
+	// The other tests aren't limited, so stop limiting again afterwards.
+	defer ratelimit.Configure(ratelimit.Config{})
+
+	original := model.Pad{
+		ID:       "rate-limited",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	ratelimit.Configure(ratelimit.Config{
+		Pad: ratelimit.Rate{Burst: 2, PerMinute: 1},
+	})
+
+	for i := 0; i < 2; i++ {
+		res, err, errorResponse := getRequest(t, client, nil, baseURL+"pad/"+original.ID)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("rate limit: request within the limit failed (%v, %v)", res.Status, errorResponse.Error)
+			return
+		}
+	}
+
+	res, err, _ := getRequest(t, client, nil, baseURL+"pad/"+original.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusTooManyRequests || res.Header.Get("Retry-After") == "" {
+		t.Errorf("rate limit: request over the pad's limit wasn't limited (%v, %q)", res.Status, res.Header.Get("Retry-After"))
+	}
+
+	ratelimit.Configure(ratelimit.Config{
+		FailedProof: ratelimit.Rate{Burst: 2, PerMinute: 1},
+	})
+
+	incorrect := model.Pad{
+		ID:      original.ID,
+		Content: "OTHER-ENCRYPTED-STUFF-HERE",
+		Proof:   "OTHER-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	for i := 0; i < 2; i++ {
+		res, err, errorResponse := request(t, client, incorrect, nil, http.MethodPut, baseURL+"pad")
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != http.StatusForbidden {
+			t.Errorf("rate limit: expected status forbidden (%v, %v)", res.Status, errorResponse.Error)
+			return
+		}
+	}
+
+	// Even the correct proof is limited, so proofs can't be guessed until the limit is hit.
+	correct := incorrect
+	correct.Proof = original.NewProof
+
+	res, err, _ = request(t, client, correct, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusTooManyRequests || res.Header.Get("Retry-After") != "60" {
+		t.Errorf("rate limit: request after too many failed proofs wasn't limited (%v, %q)", res.Status, res.Header.Get("Retry-After"))
+	}
+
+	output, err := pad.FromID(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if output.Content != original.Content {
+		t.Errorf("rate limit: limited request updated the pad (%q)", output.Content)
+	}
+}
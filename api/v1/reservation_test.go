@@ -0,0 +1,146 @@
+package v1
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+)
+
+// head tests checking if pads exist without downloading them.
+func head(t *testing.T, client *http.Client) {
+	res, err := client.Head(baseURL + "pad/test")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("head: existing pad returned %v", res.Status)
+	}
+
+	if res.Header.Get("ETag") == "" {
+		t.Error("head: no etag was returned")
+	}
+
+	res, err = client.Head(baseURL + "pad/non-existant")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("head: non-existant pad returned %v", res.Status)
+	}
+}
+
+// available tests checking if pad IDs are available.
+func available(t *testing.T, client *http.Client) {
+	output := &model.Availability{}
+
+	res, err, errorResponse := getRequest(t, client, output, baseURL+"pad/test/available")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("available: could not check existing pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	if output.Available {
+		t.Error("available: existing pad is available")
+	}
+
+	output = &model.Availability{}
+
+	res, err, errorResponse = getRequest(t, client, output, baseURL+"pad/non-existant/available")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("available: could not check non-existant pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	if !output.Available {
+		t.Error("available: non-existant pad is not available")
+	}
+}
+
+// reserve tests that reserved IDs can only be created with the reservation token.
+func reserve(t *testing.T, client *http.Client) {
+	err := pad.Remove("reserved-pad")
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	reservation := &model.Reservation{}
+
+	res, err, errorResponse := request(t, client, model.Reservation{ID: "reserved-pad"}, reservation, http.MethodPost, baseURL+"reservation")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("reserve: could not reserve pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	// The ID can't be reserved twice.
+	res, err, _ = request(t, client, model.Reservation{ID: "reserved-pad"}, nil, http.MethodPost, baseURL+"reservation")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusConflict {
+		t.Errorf("reserve: reserved pad was reserved again (%v)", res.Status)
+	}
+
+	output := &model.Availability{}
+
+	_, err, _ = getRequest(t, client, output, baseURL+"pad/reserved-pad/available")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if output.Available {
+		t.Error("reserve: reserved pad is available")
+	}
+
+	data := model.Pad{
+		ID:       "reserved-pad",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	// Without the token the ID can't be used.
+	res, err, _ = request(t, client, data, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusConflict {
+		t.Errorf("reserve: reserved pad was created without the token (%v)", res.Status)
+	}
+
+	data.Reservation = reservation.Token
+
+	res, err, errorResponse = request(t, client, data, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("reserve: could not create reserved pad (%v, %v)", res.Status, errorResponse.Error)
+	}
+}
@@ -0,0 +1,172 @@
+package v1
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+)
+
+// revisions tests that a pad's history is kept, and pruned to the configured length.
+func revisions(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "revisions",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	updated := model.Pad{
+		ID:      "revisions",
+		Content: "OTHER-ENCRYPTED-STUFF-HERE",
+		Proof:   "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	// Update the pad until it is at revision 4, so revision 1 is pruned.
+	for i := 0; i < 3; i++ {
+		res, err, errorResponse := request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("revisions: could not update pad (%v, %v)", res.Status, errorResponse.Error)
+			return
+		}
+	}
+
+	var output []model.Revision
+
+	res, err, errorResponse := getRequest(t, client, &output, baseURL+"pad/"+original.ID+"/revisions")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("revisions: could not get revisions (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	if len(output) != 3 || output[0].Revision != 2 || output[2].Revision != 4 {
+		t.Errorf("revisions: expected revisions 2 to 4, got %v (%v, %v)", output, res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("revisions: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// revisionPruned tests getting a revision which has been pruned from the history.
+func revisionPruned(t *testing.T, client *http.Client) {
+	res, err, errorResponse := getRequest(t, client, nil, baseURL+"pad/revisions/revisions/1")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("revision pruned: expected status not found (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("revision pruned: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// restore tests restoring an old revision of a pad.
+func restore(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "restore",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	updated := model.Pad{
+		ID:      "restore",
+		Content: "OTHER-ENCRYPTED-STUFF-HERE",
+		Proof:   "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	expected := model.Pad{
+		ID:       "restore",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		Revision: 3,
+		Size:     20,
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	res, err, errorResponse := request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	body := model.Pad{
+		Proof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	res, err, errorResponse = request(t, client, body, nil, http.MethodPost, baseURL+"pad/restore/revisions/1/restore")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("restore: could not restore revision (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	output := &model.Pad{}
+
+	res, err, errorResponse = getRequest(t, client, output, baseURL+"pad/"+original.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if expected != *output {
+		t.Errorf("restore: output differs from expected after restoring (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("restore: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// restoreIncorrectProof tests restoring an old revision of a pad with a proof that doesn't match.
+func restoreIncorrectProof(t *testing.T, client *http.Client) {
+	body := model.Pad{
+		Proof: "OTHER-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	res, err, errorResponse := request(t, client, body, nil, http.MethodPost, baseURL+"pad/restore/revisions/2/restore")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("restore incorrect proof: expected status forbidden (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("restore incorrect proof: success (%v, %v)", res.Status, errorResponse.Error)
+}
@@ -0,0 +1,154 @@
+package v1
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+)
+
+// rotateKey tests rotating a pad's key, which must re-encrypt its history and attachments and revokes its editors and read token.
+// Pads with a read token must be explicitly made public to rotate without a new one.
+func rotateKey(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:           "rotated-pad",
+		Content:      "ENCRYPTED-STUFF-HERE",
+		NewProof:     "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		NewReadToken: "READ-TOKEN-ABCDEFGHIJKLMNOPQRSTU",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	editorProof, err := pad.HashProof("EDITOR-KEY-ABCDEFGHIJKLMNOPQRSTU")
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Store.PutEditor(original.ID, model.Editor{
+		Name:  "alice",
+		Proof: editorProof,
+	})
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Store.PutAttachment(original.ID, model.Attachment{
+		ID:      "attachment",
+		Size:    20,
+		Created: time.Now().Unix(),
+	}, []byte("ENCRYPTED-ATTACHMENT"), 1)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	url := baseURL + "pad/" + original.ID + "/rotate"
+	rotation := model.Rotation{
+		Pad: model.Pad{
+			Content:  "ROTATED-STUFF-HERE",
+			Proof:    original.NewProof,
+			NewProof: "NEW-PROOF-KEY-ABCDEFGHIJKLMNOPQR",
+		},
+		Revisions: map[int64]model.Ciphertext{
+			1: "ROTATED-REVISION-HERE",
+		},
+	}
+
+	// The attachment wasn't re-encrypted.
+	res, err, _ := request(t, client, rotation, nil, http.MethodPost, url)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("rotate key: rotation missing an attachment was accepted (%v)", res.Status)
+	}
+
+	rotation.Attachments = map[string][]byte{
+		"attachment": []byte("ROTATED-ATTACHMENT"),
+	}
+
+	editorRotation := rotation
+	editorRotation.Editor = "alice"
+	editorRotation.Proof = "EDITOR-KEY-ABCDEFGHIJKLMNOPQRSTU"
+
+	res, err, _ = request(t, client, editorRotation, nil, http.MethodPost, url)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("rotate key: editor could rotate the key (%v)", res.Status)
+	}
+
+	// The read token is revoked, so the pad can't become public without asking for it.
+	res, err, _ = request(t, client, rotation, nil, http.MethodPost, url)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("rotate key: rotation without a new read token was accepted (%v)", res.Status)
+	}
+
+	rotation.Public = true
+
+	res, err, errorResponse := request(t, client, rotation, nil, http.MethodPost, url)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK || res.Header.Get("ETag") != `"2"` {
+		t.Errorf("rotate key: could not rotate key (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	output, err := pad.FromID(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if output.Content != rotation.Content || !pad.VerifyProof(output.Proof, rotation.NewProof) || output.ReadToken != "" {
+		t.Errorf("rotate key: rotated pad doesn't match (%+v)", output)
+	}
+
+	revision, err := pad.Store.Revision(original.ID, 1)
+	if err != nil || revision.Content != rotation.Revisions[1] {
+		t.Errorf("rotate key: revision wasn't re-encrypted (%q, %v)", revision.Content, err)
+	}
+
+	_, data, err := pad.Store.Attachment(original.ID, "attachment")
+	if err != nil || string(data) != "ROTATED-ATTACHMENT" {
+		t.Errorf("rotate key: attachment wasn't re-encrypted (%q, %v)", data, err)
+	}
+
+	editors, err := pad.Store.Editors(original.ID)
+	if err != nil || len(editors) != 0 {
+		t.Errorf("rotate key: editors weren't removed (%v, %v)", editors, err)
+	}
+
+	// The old proof can no longer rotate the key back.
+	res, err, _ = request(t, client, rotation, nil, http.MethodPost, url)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("rotate key: old proof could rotate the key (%v)", res.Status)
+	}
+}
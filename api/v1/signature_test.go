@@ -0,0 +1,243 @@
+package v1
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+)
+
+// signUpdate signs an update of a pad from a revision.
+func signUpdate(key ed25519.PrivateKey, update model.Pad, revision int64) string {
+	hash := sha256.Sum256([]byte(update.Content))
+	message := "cryptopad-update\n" + update.ID + "\n" + hex.EncodeToString(hash[:]) + "\n" + strconv.FormatInt(revision, 10) + "\n" +
+		update.NewProof + "\n" + update.NewPublicKey + "\n" + update.NewReadToken + "\n" +
+		strconv.FormatInt(update.TTL, 10) + "\n" + strconv.FormatInt(update.ExpiresAt, 10) + "\n" +
+		strconv.FormatBool(update.BurnAfterReading) + "\n" + strconv.FormatInt(update.Compacted, 10)
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(message)))
+}
+
+// signDelete signs the deletion of a pad at a revision.
+func signDelete(key ed25519.PrivateKey, id string, revision int64) string {
+	message := "cryptopad-delete\n" + id + "\n" + strconv.FormatInt(revision, 10)
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(message)))
+}
+
+// putSigned tests creating a pad owned by a public key, updating it with a signature, and that signatures can't be replayed.
+func putSigned(t *testing.T, client *http.Client) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	original := model.Pad{
+		ID:           "signed-pad",
+		Content:      "ENCRYPTED-STUFF-HERE",
+		NewPublicKey: base64.StdEncoding.EncodeToString(public),
+	}
+
+	err = pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	res, err, errorResponse := request(t, client, original, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("put signed: could not put new pad with only a public key (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	updated := model.Pad{
+		ID:      "signed-pad",
+		Content: "OTHER-ENCRYPTED-STUFF-HERE",
+	}
+
+	updated.Signature = signUpdate(private, updated, 1)
+
+	res, err, errorResponse = request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("put signed: could not update pad with signature (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	// The signature was for revision 1, so it can't be used again now the pad is at revision 2.
+	res, err, errorResponse = request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("put signed: expected status forbidden replaying a signature (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("put signed: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// putSignedTampered tests that a signed update can't be altered by whoever relays it, such as to take over the pad with their own key.
+func putSignedTampered(t *testing.T, client *http.Client) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	attacker, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	original := model.Pad{
+		ID:           "signed-tamper",
+		Content:      "ENCRYPTED-STUFF-HERE",
+		NewPublicKey: base64.StdEncoding.EncodeToString(public),
+	}
+
+	err = pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	updated := model.Pad{
+		ID:      "signed-tamper",
+		Content: "OTHER-ENCRYPTED-STUFF-HERE",
+	}
+
+	updated.Signature = signUpdate(private, updated, 1)
+
+	tampered := updated
+	tampered.NewPublicKey = base64.StdEncoding.EncodeToString(attacker)
+
+	res, err, errorResponse := request(t, client, tampered, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("put signed tampered: expected status forbidden changing the new public key (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	stored, err := pad.FromID(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if stored.PublicKey != original.NewPublicKey || stored.Revision != 1 {
+		t.Errorf("put signed tampered: pad was changed by a tampered update")
+		return
+	}
+
+	// The signature itself was valid, so the update as it was signed still applies.
+	res, err, errorResponse = request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("put signed tampered: could not update pad with the untampered signature (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("put signed tampered: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// putSignedNoProof tests that an empty proof can't be used to update a pad owned by a public key.
+func putSignedNoProof(t *testing.T, client *http.Client) {
+	updated := model.Pad{
+		ID:      "signed-pad",
+		Content: "MALICIOUS-STUFF-HERE",
+	}
+
+	res, err, errorResponse := request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("put signed no proof: expected status forbidden (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("put signed no proof: success (%v, %v)", res.Status, errorResponse.Error)
+}
+
+// deleteSigned tests deleting a pad owned by a public key with a signature.
+func deleteSigned(t *testing.T, client *http.Client) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	original := model.Pad{
+		ID:           "signed-delete",
+		Content:      "ENCRYPTED-STUFF-HERE",
+		NewPublicKey: base64.StdEncoding.EncodeToString(public),
+	}
+
+	err = pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	body := model.Pad{
+		ID:        "signed-delete",
+		Signature: signDelete(private, "signed-delete", 1),
+	}
+
+	res, err, errorResponse := request(t, client, body, nil, http.MethodDelete, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("delete signed: could not delete pad with signature (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	_, err = pad.FromID(original.ID)
+	if err != sql.ErrNoRows {
+		t.Errorf("delete signed: pad still exists (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	t.Logf("delete signed: success (%v, %v)", res.Status, errorResponse.Error)
+}
@@ -0,0 +1,182 @@
+package v1
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+	"github.com/gorilla/websocket"
+)
+
+// subscribe tests that subscribers receive updates to a pad, and are closed when it is deleted.
+func subscribe(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "subscribe-pad",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	res, err, errorResponse := request(t, client, original, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("subscribe: could not put new pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	url := "ws" + strings.TrimPrefix(baseURL, "http") + "pad/" + original.ID + "/subscribe"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(timeout))
+
+	updated := model.Pad{
+		ID:      original.ID,
+		Content: "OTHER-ENCRYPTED-STUFF-HERE",
+		Proof:   original.NewProof,
+	}
+
+	res, err, errorResponse = request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("subscribe: could not update pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	var event model.Event
+	err = conn.ReadJSON(&event)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if event.Type != pad.EventUpdated || event.Revision != 2 || event.Content != updated.Content {
+		t.Errorf("subscribe: unexpected update event %+v", event)
+	}
+
+	res, err, errorResponse = request(t, client, updated, nil, http.MethodDelete, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("subscribe: could not delete pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	event = model.Event{}
+	err = conn.ReadJSON(&event)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if event.Type != pad.EventDeleted {
+		t.Errorf("subscribe: unexpected delete event %+v", event)
+	}
+
+	// The subscription is closed once the pad is deleted.
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("subscribe: subscription wasn't closed (%v)", err)
+	}
+}
+
+// subscribeRevoked tests subscribers stop receiving a pad's events once its read token is changed.
+func subscribeRevoked(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:           "subscribe-revoke",
+		Content:      "ENCRYPTED-STUFF-HERE",
+		NewProof:     "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		NewReadToken: "READ-TOKEN-ABCDEFGHIJKLMNOPQRSTU",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	url := "ws" + strings.TrimPrefix(baseURL, "http") + "pad/" + original.ID + "/subscribe?token=" + original.NewReadToken
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(timeout))
+
+	updated := model.Pad{
+		ID:           original.ID,
+		Content:      "OTHER-ENCRYPTED-STUFF-HERE",
+		Proof:        original.NewProof,
+		NewReadToken: "NEW-READ-TOKEN-ABCDEFGHIJKLMNOPQ",
+	}
+
+	res, err, errorResponse := request(t, client, updated, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("subscribe revoked: could not update pad (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	var event model.Event
+	err = conn.ReadJSON(&event)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if event.Type != pad.EventRevoked || event.Revision != 2 || event.Content != "" {
+		t.Errorf("subscribe revoked: unexpected revoke event %+v", event)
+	}
+
+	// The old read token holder doesn't receive anything else.
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("subscribe revoked: subscription wasn't closed (%v)", err)
+	}
+}
+
+// subscribeNonExistant tests subscribing to a pad which doesn't exist.
+func subscribeNonExistant(t *testing.T, client *http.Client) {
+	res, err, _ := getRequest(t, client, nil, baseURL+"pad/non-existant/subscribe")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("subscribe non existant: returned %v", res.Status)
+	}
+}
@@ -0,0 +1,452 @@
+package v1
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+)
+
+// rawRequest sends a request with a raw body, returning the raw response body.
+func rawRequest(client *http.Client, method, url string, body []byte) (res *http.Response, output []byte, err error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	res, err = client.Do(req)
+	if err != nil {
+		return
+	}
+
+	defer res.Body.Close()
+
+	output, err = ioutil.ReadAll(res.Body)
+	return
+}
+
+// uploadChunks tests uploading a pad's content in chunks, resuming the upload, and downloading the chunks.
+func uploadChunks(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "chunked-pad",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	chunks := [][]byte{
+		[]byte("ENCRYPTED-CHUNK0"),
+		[]byte("ENCRYPTED-CHUNK1"),
+		[]byte("CHUNK2!!"),
+	}
+
+	start := model.Pad{
+		Proof:  original.NewProof,
+		Chunks: 3,
+		Size:   40,
+	}
+
+	upload := &model.Upload{}
+
+	res, err, errorResponse := request(t, client, start, upload, http.MethodPost, baseURL+"pad/"+original.ID+"/uploads")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("upload chunks: could not start upload (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	uploadURL := baseURL + "uploads/" + upload.ID
+
+	// Upload the first and last chunks, as if the upload was interrupted.
+	for _, index := range []int{0, 2} {
+		res, _, err = rawRequest(client, http.MethodPut, uploadURL+"/chunks/"+strconv.Itoa(index), chunks[index])
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("upload chunks: could not upload chunk %v (%v)", index, res.Status)
+			return
+		}
+	}
+
+	res, _, err = rawRequest(client, http.MethodPut, uploadURL+"/chunks/1", []byte("CHUNK-LARGER-THAN-16"))
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("upload chunks: chunk larger than the maximum was uploaded (%v)", res.Status)
+	}
+
+	res, err, _ = request(t, client, nil, nil, http.MethodPost, uploadURL+"/commit")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("upload chunks: incomplete upload was committed (%v)", res.Status)
+	}
+
+	// Resume the upload with the chunks which haven't been received.
+	status := &model.Upload{}
+
+	_, err, _ = getRequest(t, client, status, uploadURL)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(status.Received) != 2 || status.Received[0].Index != 0 || status.Received[1].Index != 2 {
+		t.Errorf("upload chunks: unexpected received chunks %+v", status.Received)
+		return
+	}
+
+	res, _, err = rawRequest(client, http.MethodPut, uploadURL+"/chunks/1", chunks[1])
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("upload chunks: could not upload chunk 1 (%v)", res.Status)
+		return
+	}
+
+	res, err, errorResponse = request(t, client, nil, nil, http.MethodPost, uploadURL+"/commit")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK || res.Header.Get("ETag") != `"2"` {
+		t.Errorf("upload chunks: could not commit upload (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	output := &model.Pad{}
+
+	_, err, _ = getRequest(t, client, output, baseURL+"pad/"+original.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if output.Content != "" || output.Chunks != 3 || output.Size != 40 {
+		t.Errorf("upload chunks: unexpected pad after committing %+v", output)
+	}
+
+	for index, expected := range chunks {
+		res, data, err := rawRequest(client, http.MethodGet, baseURL+"pad/"+original.ID+"/chunks/"+strconv.Itoa(index), nil)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != http.StatusOK || !bytes.Equal(data, expected) {
+			t.Errorf("upload chunks: chunk %v differs (%v)", index, res.Status)
+		}
+	}
+
+	// Committed uploads are used up.
+	res, err, _ = request(t, client, nil, nil, http.MethodPost, uploadURL+"/commit")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("upload chunks: upload was committed twice (%v)", res.Status)
+	}
+
+	// Rotations can't re-encrypt chunks, so chunked pads can't be rotated.
+	rotation := model.Rotation{
+		Pad: model.Pad{
+			Content:  "ROTATED-STUFF-HERE",
+			Proof:    original.NewProof,
+			NewProof: "NEW-PROOF-KEY-ABCDEFGHIJKLMNOPQR",
+		},
+		Revisions: map[int64]model.Ciphertext{
+			1: "ROTATED-REVISION-HERE",
+			2: "ROTATED-REVISION-HERE",
+		},
+	}
+
+	res, err, _ = request(t, client, rotation, nil, http.MethodPost, baseURL+"pad/"+original.ID+"/rotate")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusConflict {
+		t.Errorf("upload chunks: chunked pad was rotated (%v)", res.Status)
+	}
+}
+
+// uploadConcurrent tests chunks sent at once can't add up to more than the upload's size.
+func uploadConcurrent(t *testing.T, client *http.Client) {
+	original := model.Pad{
+		ID:       "chunked-race",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	err := pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	start := model.Pad{
+		Proof:  original.NewProof,
+		Chunks: 3,
+		Size:   24,
+	}
+
+	upload := &model.Upload{}
+
+	res, err, errorResponse := request(t, client, start, upload, http.MethodPost, baseURL+"pad/"+original.ID+"/uploads")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("upload concurrent: could not start upload (%v, %v)", res.Status, errorResponse.Error)
+		return
+	}
+
+	uploadURL := baseURL + "uploads/" + upload.ID
+
+	// Each chunk fits on its own, but any two are larger than the upload.
+	var wg sync.WaitGroup
+	for index := 0; index < start.Chunks; index++ {
+		wg.Add(1)
+
+		go func(index int) {
+			defer wg.Done()
+			rawRequest(client, http.MethodPut, uploadURL+"/chunks/"+strconv.Itoa(index), []byte("ENCRYPTED-CHUNK"+strconv.Itoa(index)))
+		}(index)
+	}
+
+	wg.Wait()
+
+	received, err := pad.Store.UploadChunks(upload.ID)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(received) != 1 {
+		t.Errorf("upload concurrent: upload has %v chunks rather than 1", len(received))
+	}
+
+	err = pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+// uploadTooLarge tests starting an upload larger than the maximum pad size.
+func uploadTooLarge(t *testing.T, client *http.Client) {
+	start := model.Pad{
+		Proof:  "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+		Chunks: 5,
+		Size:   65,
+	}
+
+	res, err, _ := request(t, client, start, nil, http.MethodPost, baseURL+"pad/chunked-pad/uploads")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("upload too large: upload was started (%v)", res.Status)
+	}
+}
+
+// uploadSigned tests a signed upload can only be started once, even after it expires, that only the signed chunks can
+// be uploaded, that each pad can only have so many open uploads, and that staged chunks count towards the store's usage.
+func uploadSigned(t *testing.T, client *http.Client) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	original := model.Pad{
+		ID:           "chunked-signed",
+		Content:      "ENCRYPTED-STUFF-HERE",
+		NewPublicKey: base64.StdEncoding.EncodeToString(public),
+	}
+
+	err = pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = pad.Insert(original)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	url := baseURL + "pad/" + original.ID + "/uploads"
+
+	chunk := []byte("CHUNK0!!")
+	hash := sha256.Sum256(chunk)
+	hashes := hex.EncodeToString(hash[:])
+
+	// start signs starting an upload of the 8 byte chunk with an ID.
+	start := func(b byte) model.Pad {
+		id := base64.RawURLEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+		manifest := sha256.Sum256([]byte(hashes))
+		message := "cryptopad-upload\n" + original.ID + "\n" + id + "\n1\n8\n" + hex.EncodeToString(manifest[:]) + "\n1"
+
+		return model.Pad{
+			Chunks:    1,
+			Size:      8,
+			Upload:    id,
+			Hashes:    hashes,
+			Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(private, []byte(message))),
+		}
+	}
+
+	// A relay can't swap the signed hashes for those of its own chunks, and signed uploads must send hashes.
+	tampered := start(4)
+	other := sha256.Sum256([]byte("EVIL!!!!"))
+	tampered.Hashes = hex.EncodeToString(other[:])
+
+	noHashes := start(5)
+	noHashes.Hashes = ""
+
+	// The same signed request can't start another upload, and the test config allows 2 open uploads per pad.
+	for i, step := range []struct {
+		upload model.Pad
+		status int
+	}{
+		{start(1), http.StatusCreated},
+		{start(1), http.StatusConflict},
+		{tampered, http.StatusForbidden},
+		{noHashes, http.StatusBadRequest},
+		{start(2), http.StatusCreated},
+		{start(3), http.StatusConflict},
+	} {
+		res, err, errorResponse := request(t, client, step.upload, nil, http.MethodPost, url)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != step.status {
+			t.Errorf("upload signed: request %v was %v rather than %v (%v)", i, res.Status, step.status, errorResponse.Error)
+			return
+		}
+	}
+
+	before, err := pad.Store.Usage()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	// Only the chunk which was signed can be uploaded.
+	res, _, err := rawRequest(client, http.MethodPut, baseURL+"uploads/"+start(1).Upload+"/chunks/0", []byte("EVIL!!!!"))
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("upload signed: uploaded a chunk which wasn't signed (%v)", res.Status)
+		return
+	}
+
+	res, _, err = rawRequest(client, http.MethodPut, baseURL+"uploads/"+start(1).Upload+"/chunks/0", chunk)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("upload signed: could not upload chunk (%v)", res.Status)
+		return
+	}
+
+	after, err := pad.Store.Usage()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if after.Bytes != before.Bytes+8 {
+		t.Errorf("upload signed: staged chunk wasn't counted in usage (%v, then %v)", before.Bytes, after.Bytes)
+	}
+
+	// Once the uploads expire and are swept their chunks are removed, but their signatures still can't start them again.
+	err = pad.RemoveExpired(time.Now().Unix() + 120)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	received, err := pad.Store.UploadChunks(start(1).Upload)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(received) != 0 {
+		t.Errorf("upload signed: expired upload kept its chunks (%v)", received)
+	}
+
+	res, err, errorResponse := request(t, client, start(1), nil, http.MethodPost, url)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusConflict || errorResponse.Error != "an upload with the id already exists" {
+		t.Errorf("upload signed: replayed an expired upload (%v, %v)", res.Status, errorResponse.Error)
+	}
+
+	err = pad.Remove(original.ID)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+	"github.com/VolticFroogo/cryptopad-server/ratelimit"
 	"github.com/gorilla/mux"
 )
 
@@ -12,8 +14,37 @@ const (
 )
 
 // Handle adds the v1 API endpoints.
+// Responses are encoded in whichever format each request accepts, and requests are rate limited.
 func Handle(r *mux.Router) {
-	r.Handle(urlPrefix+"pad/{id}", http.HandlerFunc(pad.Get)).Methods(http.MethodGet)
-	r.Handle(urlPrefix+"pad", http.HandlerFunc(pad.Put)).Methods(http.MethodPut)
-	r.Handle(urlPrefix+"pad", http.HandlerFunc(pad.Delete)).Methods(http.MethodDelete)
+	api := r.NewRoute().Subrouter()
+	api.Use(helper.Negotiate, ratelimit.Limit)
+
+	api.Handle(urlPrefix+"pad/{id}", http.HandlerFunc(pad.Get)).Methods(http.MethodGet)
+	api.Handle(urlPrefix+"pad/{id}", http.HandlerFunc(pad.Head)).Methods(http.MethodHead)
+	api.Handle(urlPrefix+"pad/{id}/available", http.HandlerFunc(pad.Available)).Methods(http.MethodGet)
+	api.Handle(urlPrefix+"reservation", http.HandlerFunc(pad.Reserve)).Methods(http.MethodPost)
+	api.Handle(urlPrefix+"work", http.HandlerFunc(pad.Work)).Methods(http.MethodPost)
+	api.Handle(urlPrefix+"pad", http.HandlerFunc(pad.Put)).Methods(http.MethodPut)
+	api.Handle(urlPrefix+"pad", http.HandlerFunc(pad.Delete)).Methods(http.MethodDelete)
+	api.Handle(urlPrefix+"pad/{id}/subscribe", http.HandlerFunc(pad.Subscribe)).Methods(http.MethodGet)
+	api.Handle(urlPrefix+"pad/{id}/events", http.HandlerFunc(pad.Events)).Methods(http.MethodGet)
+	api.Handle(urlPrefix+"pad/{id}/ops", http.HandlerFunc(pad.Ops)).Methods(http.MethodGet)
+	api.Handle(urlPrefix+"pad/{id}/ops", http.HandlerFunc(pad.AppendOp)).Methods(http.MethodPost)
+	api.Handle(urlPrefix+"pad/{id}/uploads", http.HandlerFunc(pad.StartUpload)).Methods(http.MethodPost)
+	api.Handle(urlPrefix+"pad/{id}/chunks/{index}", http.HandlerFunc(pad.Chunk)).Methods(http.MethodGet)
+	api.Handle(urlPrefix+"uploads/{upload}", http.HandlerFunc(pad.GetUpload)).Methods(http.MethodGet)
+	api.Handle(urlPrefix+"uploads/{upload}/chunks/{index}", http.HandlerFunc(pad.PutChunk)).Methods(http.MethodPut)
+	api.Handle(urlPrefix+"uploads/{upload}/commit", http.HandlerFunc(pad.CommitUpload)).Methods(http.MethodPost)
+	api.Handle(urlPrefix+"pad/{id}/attachments", http.HandlerFunc(pad.Attachments)).Methods(http.MethodGet)
+	api.Handle(urlPrefix+"pad/{id}/attachments", http.HandlerFunc(pad.PostAttachment)).Methods(http.MethodPost)
+	api.Handle(urlPrefix+"pad/{id}/attachments/{attachment}", http.HandlerFunc(pad.Attachment)).Methods(http.MethodGet)
+	api.Handle(urlPrefix+"pad/{id}/attachments/{attachment}", http.HandlerFunc(pad.DeleteAttachment)).Methods(http.MethodDelete)
+	api.Handle(urlPrefix+"pad/{id}/rotate", http.HandlerFunc(pad.RotateKey)).Methods(http.MethodPost)
+	api.Handle(urlPrefix+"pad/{id}/challenge", http.HandlerFunc(pad.Challenge)).Methods(http.MethodPost)
+	api.Handle(urlPrefix+"pad/{id}/editors", http.HandlerFunc(pad.Editors)).Methods(http.MethodGet)
+	api.Handle(urlPrefix+"pad/{id}/editors/{name}", http.HandlerFunc(pad.PutEditor)).Methods(http.MethodPut)
+	api.Handle(urlPrefix+"pad/{id}/editors/{name}", http.HandlerFunc(pad.DeleteEditor)).Methods(http.MethodDelete)
+	api.Handle(urlPrefix+"pad/{id}/revisions", http.HandlerFunc(pad.Revisions)).Methods(http.MethodGet)
+	api.Handle(urlPrefix+"pad/{id}/revisions/{rev}", http.HandlerFunc(pad.Revision)).Methods(http.MethodGet)
+	api.Handle(urlPrefix+"pad/{id}/revisions/{rev}/restore", http.HandlerFunc(pad.Restore)).Methods(http.MethodPost)
 }
@@ -4,35 +4,39 @@ import (
 	"bytes"
 	"encoding/json"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
 	"github.com/VolticFroogo/cryptopad-server/db"
 	"github.com/gorilla/mux"
 )
 
 const (
-	location = "http://localhost"
-	port     = ":8080"
-	baseURL  = location + port + urlPrefix
-	dbCfgDir = "../../configs/db_test.ini"
-	timeout  = time.Second * 2
+	location  = "http://localhost"
+	port      = ":8080"
+	baseURL   = location + port + urlPrefix
+	padCfgDir = "../../configs/pad_test.ini"
+	timeout   = time.Second * 2
 )
 
+// dbCfgDirs are the databases every test is run against, so each store is tested the same way.
+var dbCfgDirs = []string{
+	"../../configs/db_test.ini",
+	"../../configs/db_sqlite_test.ini",
+}
+
 // ErrorResponse is the type used for error JSON responses.
 type ErrorResponse struct {
 	Error string
 }
 
 func TestV1(t *testing.T) {
-	// Initialise the DB.
-	err := db.Init(dbCfgDir)
-	if err != nil {
-		t.Error(err.Error())
-		return
-	}
-
 	// Start handling incoming requests.
 	// Create a new Mux Router with strict slash.
 	r := mux.NewRouter()
@@ -41,14 +45,46 @@ func TestV1(t *testing.T) {
 	// Handle v1 of the API.
 	Handle(r)
 
+	// Listen before serving so requests can't race the server starting.
+	listener, err := net.Listen("tcp", port)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
 	// Start serving on a seperate thread.
-	go http.ListenAndServe(port, r)
+	go http.Serve(listener, r)
 
 	// Create an HTTP client to make requests with.
 	client := &http.Client{
 		Timeout: timeout,
 	}
 
+	for _, dbCfgDir := range dbCfgDirs {
+		t.Run(strings.TrimSuffix(filepath.Base(dbCfgDir), ".ini"), func(t *testing.T) {
+			// Initialise the DB.
+			err := db.Init(dbCfgDir)
+			if err != nil {
+				t.Error(err.Error())
+				return
+			}
+
+			defer db.Close()
+
+			// Select the pad store for the database.
+			err = pad.Init(padCfgDir)
+			if err != nil {
+				t.Error(err.Error())
+				return
+			}
+
+			testV1(t, client)
+		})
+	}
+}
+
+// testV1 runs every test against the initialised store.
+func testV1(t *testing.T, client *http.Client) {
 	// Run all get related tests.
 	get(t, client)
 	getIDTooShort(t, client)
@@ -64,6 +100,11 @@ func TestV1(t *testing.T) {
 	putNoNewProof(t, client)
 	putIDTooShort(t, client)
 	putIDTooLong(t, client)
+	putLegacyProof(t, client)
+	putIfMatch(t, client)
+	putStaleIfMatch(t, client)
+	putEscaped(t, client)
+	putTooLarge(t, client)
 
 	// Run all delete related tests.
 	deletePad(t, client)
@@ -72,6 +113,91 @@ func TestV1(t *testing.T) {
 	deletePadInvalidProofLen(t, client)
 	deletePadIDTooShort(t, client)
 	deletePadIDTooLong(t, client)
+
+	// Run all revision related tests.
+	revisions(t, client)
+	revisionPruned(t, client)
+	restore(t, client)
+	restoreIncorrectProof(t, client)
+
+	// Run all expiry related tests.
+	putTTL(t, client)
+	putExpired(t, client)
+	getExpired(t, client)
+	removeExpired(t, client)
+
+	// Run all burn after reading related tests.
+	getBurn(t, client)
+	getBurnConcurrent(t, client)
+	burnLockout(t, client)
+
+	// Run all challenge related tests.
+	putChallenge(t, client)
+	putIncorrectResponse(t, client)
+	challengeNoPublicKey(t, client)
+
+	// Run all signature related tests.
+	putSigned(t, client)
+	putSignedTampered(t, client)
+	putSignedNoProof(t, client)
+	deleteSigned(t, client)
+
+	// Run all capability related tests.
+	getReadToken(t, client)
+	putEditor(t, client)
+	deleteEditor(t, client)
+	editorSigned(t, client)
+
+	// Run all availability related tests.
+	head(t, client)
+	available(t, client)
+	reserve(t, client)
+
+	// Run all subscription related tests.
+	subscribe(t, client)
+	subscribeRevoked(t, client)
+	subscribeNonExistant(t, client)
+	events(t, client)
+
+	// Run all op log related tests.
+	ops(t, client)
+	opsFull(t, client)
+
+	// Run all chunked upload related tests.
+	uploadChunks(t, client)
+	uploadConcurrent(t, client)
+	uploadTooLarge(t, client)
+	uploadSigned(t, client)
+
+	// Run all attachment related tests.
+	attachments(t, client)
+	attachmentsSigned(t, client)
+	attachmentsConcurrent(t, client)
+
+	// Run all format related tests.
+	formats(t, client)
+
+	// Run all envelope related tests.
+	envelopes(t, client)
+
+	// Run all key rotation related tests.
+	rotateKey(t, client)
+
+	// Run all rate limiting related tests.
+	rateLimit(t, client)
+
+	// Run all lockout related tests.
+	lockout(t, client)
+
+	// Run all proof of work related tests.
+	work(t, client)
+
+	// Run all quota related tests.
+	quota(t, client)
+	quotaConcurrent(t, client)
+
+	// Run all Go client related tests.
+	clientPad(t, client)
 }
 
 func getRequest(t *testing.T, client *http.Client, output interface{}, url string) (res *http.Response, err error, errorResponse ErrorResponse) {
@@ -90,9 +216,12 @@ func getRequest(t *testing.T, client *http.Client, output interface{}, url strin
 		return
 	}
 
-	err = json.Unmarshal(outputBody, &errorResponse)
-	if err != nil {
-		return
+	// Only errors are sent as an error response, other bodies may not even be objects.
+	if res.StatusCode >= http.StatusBadRequest {
+		err = json.Unmarshal(outputBody, &errorResponse)
+		if err != nil {
+			return
+		}
 	}
 
 	if output != nil {
@@ -106,6 +235,10 @@ func getRequest(t *testing.T, client *http.Client, output interface{}, url strin
 }
 
 func request(t *testing.T, client *http.Client, body interface{}, output interface{}, method, url string) (res *http.Response, err error, errorResponse ErrorResponse) {
+	return requestWithHeader(t, client, body, output, method, url, nil)
+}
+
+func requestWithHeader(t *testing.T, client *http.Client, body interface{}, output interface{}, method, url string, header http.Header) (res *http.Response, err error, errorResponse ErrorResponse) {
 	bodyBytes, err := json.Marshal(body)
 	if err != nil {
 		return
@@ -116,6 +249,10 @@ func request(t *testing.T, client *http.Client, body interface{}, output interfa
 		return
 	}
 
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
 	res, err = client.Do(req)
 	if err != nil {
 		return
@@ -131,9 +268,12 @@ func request(t *testing.T, client *http.Client, body interface{}, output interfa
 		return
 	}
 
-	err = json.Unmarshal(outputBody, &errorResponse)
-	if err != nil {
-		return
+	// Only errors are sent as an error response, other bodies may not even be objects.
+	if res.StatusCode >= http.StatusBadRequest {
+		err = json.Unmarshal(outputBody, &errorResponse)
+		if err != nil {
+			return
+		}
 	}
 
 	if output != nil {
@@ -145,3 +285,10 @@ func request(t *testing.T, client *http.Client, body interface{}, output interfa
 
 	return
 }
+
+// matches checks if a stored pad matches an expected pad, verifying the proof against the stored hash.
+func matches(expected, output model.Pad) bool {
+	return expected.ID == output.ID &&
+		expected.Content == output.Content &&
+		pad.VerifyProof(output.Proof, expected.Proof)
+}
@@ -0,0 +1,117 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+	"github.com/VolticFroogo/cryptopad-server/client"
+)
+
+const (
+	workPadCfgDir = "../../configs/pad_work_test.ini"
+)
+
+// work tests creating pads needs a solved proof of work challenge, which gets harder as more pads are created.
+func work(t *testing.T, httpClient *http.Client) {
+	// The other tests don't need proof of work, so stop requiring it again afterwards.
+	err := pad.Init(workPadCfgDir)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	defer pad.Init(padCfgDir)
+
+	for _, id := range []string{"worked-pad", "worked-pad-0", "worked-pad-1", "worked-pad-2", "worked-client"} {
+		err = pad.Remove(id)
+		if err != nil {
+			t.Error(err.Error())
+		}
+	}
+
+	created := model.Pad{
+		ID:       "worked-pad",
+		Content:  "ENCRYPTED-STUFF-HERE",
+		NewProof: "PROOF-KEY-ABCDEFGHIJKLMNOPQRSTUV",
+	}
+
+	res, err, _ := request(t, httpClient, created, nil, http.MethodPut, baseURL+"pad")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("work: pad was created without proof of work (%v)", res.Status)
+		return
+	}
+
+	// The work config starts at 8 bits, adding one once 2 pads have been created.
+	for i, difficulty := range []int{8, 8, 9} {
+		var issued model.Work
+		res, err, errorResponse := request(t, httpClient, nil, &issued, http.MethodPost, baseURL+"work")
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != http.StatusOK || issued.Challenge == "" || issued.Difficulty != difficulty {
+			t.Errorf("work: challenge %v doesn't match (%v, %v, %+v)", i, res.Status, errorResponse.Error, issued)
+			return
+		}
+
+		created.ID = "worked-pad-" + strconv.Itoa(i)
+		created.Work = issued.Challenge
+
+		// Find a nonce which doesn't solve the challenge first.
+		for created.Nonce = ""; issued.Solves(created.Nonce); created.Nonce += "x" {
+		}
+
+		res, err, _ = request(t, httpClient, created, nil, http.MethodPut, baseURL+"pad")
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != http.StatusForbidden {
+			t.Errorf("work: pad was created with an unsolved challenge (%v)", res.Status)
+			return
+		}
+
+		// The challenge was used up by the wrong solution, so get another at the same difficulty.
+		res, err, _ = request(t, httpClient, nil, &issued, http.MethodPost, baseURL+"work")
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		created.Work = issued.Challenge
+		for n := 0; !issued.Solves(created.Nonce); n++ {
+			created.Nonce = strconv.Itoa(n)
+		}
+
+		res, err, errorResponse = request(t, httpClient, created, nil, http.MethodPut, baseURL+"pad")
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if res.StatusCode != http.StatusCreated {
+			t.Errorf("work: pad wasn't created with a solved challenge (%v, %v)", res.Status, errorResponse.Error)
+			return
+		}
+	}
+
+	// The Go client solves challenges by itself.
+	c := client.New(location + port)
+	c.HTTP = httpClient
+	c.Iterations = model.MinPBKDF2Iterations
+
+	_, err = c.Create("worked-client", "PASSWORD", "Some secret text.")
+	if err != nil {
+		t.Errorf("work: client couldn't create a pad (%v)", err)
+	}
+}
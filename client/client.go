@@ -0,0 +1,327 @@
+// Package client is a Go client for the cryptopad API, which encrypts pads as described in the README.
+// Pads are encrypted with AES-256-CBC, using a key derived from a password with PBKDF2, along with their proof.
+package client
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/helper"
+)
+
+const (
+	urlPrefix = "/api/v1/"
+)
+
+// Client talks to a cryptopad server.
+type Client struct {
+	// URL is the address of the server, such as https://cryptopad.example.com.
+	URL string
+
+	// HTTP is the client requests are sent with.
+	HTTP *http.Client
+
+	// Iterations is how many PBKDF2 iterations new keys are derived with.
+	Iterations uint32
+}
+
+// Pad is a decrypted pad.
+type Pad struct {
+	ID   string
+	Text string
+
+	// Proof is the pad's decrypted proof, which authorises changing it.
+	Proof string
+
+	// Revision is the revision the pad was loaded (or last saved) at, so saves don't overwrite other changes.
+	Revision int64
+
+	// compacted is the sequence of the last op in the loaded content.
+	compacted int64
+}
+
+// New creates a client for the server at a URL.
+func New(url string) *Client {
+	return &Client{
+		URL:        strings.TrimSuffix(url, "/"),
+		HTTP:       http.DefaultClient,
+		Iterations: DefaultIterations,
+	}
+}
+
+// Create creates a new pad with an ID, encrypting its text with a password.
+func (c *Client) Create(id, password, text string) (pad *Pad, err error) {
+	var availability model.Availability
+	_, err = c.do(http.MethodGet, "pad/"+id+"/available", nil, nil, &availability)
+	if err != nil {
+		return
+	}
+
+	if !availability.Available {
+		err = ErrUnavailable
+		return
+	}
+
+	pad = &Pad{
+		ID:   id,
+		Text: text,
+	}
+
+	pad.Proof, err = newProof()
+	if err != nil {
+		return
+	}
+
+	content, err := encrypt(password, c.Iterations, document{pad.Proof, pad.Text})
+	if err != nil {
+		return
+	}
+
+	work, nonce, err := c.work()
+	if err != nil {
+		return
+	}
+
+	res, err := c.do(http.MethodPut, "pad", model.Pad{
+		ID:       id,
+		Content:  content,
+		NewProof: pad.Proof,
+		Work:     work.Challenge,
+		Nonce:    nonce,
+	}, nil, nil)
+	if err != nil {
+		return
+	}
+
+	pad.Revision = revision(res)
+	return
+}
+
+// Load downloads a pad and decrypts it with a password.
+func (c *Client) Load(id, password string) (pad *Pad, err error) {
+	var data model.Pad
+	_, err = c.do(http.MethodGet, "pad/"+id, nil, nil, &data)
+	if err != nil {
+		return
+	}
+
+	doc, err := decrypt(password, data.Content)
+	if err != nil {
+		return
+	}
+
+	pad = &Pad{
+		ID:       data.ID,
+		Text:     doc.Text,
+		Proof:    doc.Proof,
+		Revision: data.Revision,
+
+		compacted: data.Compacted,
+	}
+
+	return
+}
+
+// Update saves a pad's text, encrypting it with a password.
+// If the pad has been changed since it was loaded, ErrPreconditionFailed is returned rather than overwriting the change.
+func (c *Client) Update(pad *Pad, password string) (err error) {
+	content, err := encrypt(password, c.Iterations, document{pad.Proof, pad.Text})
+	if err != nil {
+		return
+	}
+
+	res, err := c.do(http.MethodPut, "pad", model.Pad{
+		ID:      pad.ID,
+		Content: content,
+		Proof:   pad.Proof,
+	}, ifMatch(pad.Revision), nil)
+	if err != nil {
+		return
+	}
+
+	pad.Revision = revision(res)
+	return
+}
+
+// RotateKey re-encrypts a pad and its history with a new password, and replaces its proof, all at once.
+// The old password is needed to decrypt the history. Anyone who only knows the old password, and any editors
+// or read tokens, can no longer read or change the pad. Pads with attachments return ErrAttachments,
+// as this client doesn't know how they were encrypted.
+func (c *Client) RotateKey(pad *Pad, oldPassword, newPassword string) (err error) {
+	var attachments []model.Attachment
+	_, err = c.do(http.MethodGet, "pad/"+pad.ID+"/attachments", nil, nil, &attachments)
+	if err != nil {
+		return
+	}
+
+	if len(attachments) != 0 {
+		err = ErrAttachments
+		return
+	}
+
+	proof, err := newProof()
+	if err != nil {
+		return
+	}
+
+	rotation := model.Rotation{
+		Pad: model.Pad{
+			Proof:     pad.Proof,
+			NewProof:  proof,
+			Compacted: pad.compacted,
+		},
+		Revisions: make(map[int64]model.Ciphertext),
+	}
+
+	rotation.Content, err = encrypt(newPassword, c.Iterations, document{proof, pad.Text})
+	if err != nil {
+		return
+	}
+
+	var revisions []model.Revision
+	_, err = c.do(http.MethodGet, "pad/"+pad.ID+"/revisions", nil, nil, &revisions)
+	if err != nil {
+		return
+	}
+
+	for _, kept := range revisions {
+		var data model.Pad
+		_, err = c.do(http.MethodGet, "pad/"+pad.ID+"/revisions/"+strconv.FormatInt(kept.Revision, 10), nil, nil, &data)
+		if err != nil {
+			return
+		}
+
+		var doc document
+		doc, err = decrypt(oldPassword, data.Content)
+		if err != nil {
+			return
+		}
+
+		doc.Proof = proof
+		rotation.Revisions[kept.Revision], err = encrypt(newPassword, c.Iterations, doc)
+		if err != nil {
+			return
+		}
+	}
+
+	res, err := c.do(http.MethodPost, "pad/"+pad.ID+"/rotate", rotation, ifMatch(pad.Revision), nil)
+	if err != nil {
+		return
+	}
+
+	pad.Proof = proof
+	pad.Revision = revision(res)
+	return
+}
+
+// Delete removes a pad.
+func (c *Client) Delete(pad *Pad) (err error) {
+	_, err = c.do(http.MethodDelete, "pad", model.Pad{
+		ID:    pad.ID,
+		Proof: pad.Proof,
+	}, nil, nil)
+
+	return
+}
+
+// do sends a request to the API with a JSON body, decoding the response into output.
+// Error responses are returned as an *Error.
+func (c *Client) do(method, path string, body interface{}, header http.Header, output interface{}) (res *http.Response, err error) {
+	var reqBody []byte
+	if body != nil {
+		reqBody, err = json.Marshal(body)
+		if err != nil {
+			return
+		}
+	}
+
+	req, err := http.NewRequest(method, c.URL+urlPrefix+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	req.Header.Set("Content-Type", helper.FormatJSON)
+	req.Header.Set("Accept", helper.FormatJSON)
+
+	res, err = c.HTTP.Do(req)
+	if err != nil {
+		return
+	}
+
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		var errorResponse helper.ErrorResponse
+		json.Unmarshal(resBody, &errorResponse)
+
+		err = &Error{
+			StatusCode: res.StatusCode,
+			Message:    errorResponse.Error,
+		}
+
+		return
+	}
+
+	if output != nil && len(resBody) != 0 {
+		err = json.Unmarshal(resBody, output)
+	}
+
+	return
+}
+
+// newProof generates a random proof.
+func newProof() (string, error) {
+	// Base64 encodes every 3 bytes as 4 characters.
+	proof := make([]byte, model.ProofLen/4*3)
+	_, err := rand.Read(proof)
+	return base64.RawURLEncoding.EncodeToString(proof), err
+}
+
+// work gets a proof of work challenge and solves it, if the server needs one to create a pad.
+// Servers from before proof of work don't have challenges, so they don't need one either.
+func (c *Client) work() (work model.Work, nonce string, err error) {
+	_, err = c.do(http.MethodPost, "work", nil, nil, &work)
+	if errors.Is(err, ErrNotFound) {
+		return model.Work{}, "", nil
+	}
+
+	if err != nil || work.Difficulty == 0 {
+		return
+	}
+
+	for i := uint64(0); !work.Solves(nonce); i++ {
+		nonce = strconv.FormatUint(i, 36)
+	}
+
+	return
+}
+
+// ifMatch is the header which only applies a change if a pad is still at a revision.
+func ifMatch(revision int64) http.Header {
+	header := http.Header{}
+	header.Set("If-Match", `"`+strconv.FormatInt(revision, 10)+`"`)
+	return header
+}
+
+// revision gets the revision from a response's ETag.
+func revision(res *http.Response) int64 {
+	revision, _ := strconv.ParseInt(strings.Trim(res.Header.Get("ETag"), `"`), 10, 64)
+	return revision
+}
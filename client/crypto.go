@@ -0,0 +1,124 @@
+package client
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// DefaultIterations is how many PBKDF2 iterations keys are derived with, unless the client is told otherwise.
+	DefaultIterations = 100000
+
+	saltLen = 16
+	keyLen  = 32
+)
+
+// document is the plaintext of a pad, which includes its proof so only those who can decrypt the pad can update it.
+type document struct {
+	Proof string
+	Text  string
+}
+
+// deriveKeys derives the AES-256 and HMAC-SHA256 keys from a password with PBKDF2.
+func deriveKeys(password string, salt []byte, iterations uint32) (encKey, macKey []byte) {
+	key := pbkdf2.Key([]byte(password), salt, int(iterations), 2*keyLen, sha256.New)
+	return key[:keyLen], key[keyLen:]
+}
+
+// mac is the HMAC-SHA256 of an envelope's IV and ciphertext.
+func mac(key []byte, env model.Envelope) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(env.IV)
+	h.Write(env.Ciphertext)
+	return h.Sum(nil)
+}
+
+// encrypt encrypts a document with AES-256-CBC, in a base64 encoded envelope.
+func encrypt(password string, iterations uint32, doc document) (content model.Ciphertext, err error) {
+	plaintext, err := json.Marshal(doc)
+	if err != nil {
+		return
+	}
+
+	env := model.Envelope{
+		Version:    model.EnvelopeCBC,
+		KDF:        model.KDFPBKDF2,
+		Iterations: iterations,
+		Salt:       make([]byte, saltLen),
+		IV:         make([]byte, aes.BlockSize),
+	}
+
+	_, err = rand.Read(env.Salt)
+	if err != nil {
+		return
+	}
+
+	_, err = rand.Read(env.IV)
+	if err != nil {
+		return
+	}
+
+	encKey, macKey := deriveKeys(password, env.Salt, env.Iterations)
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return
+	}
+
+	// Pad the plaintext to whole blocks with PKCS #7.
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	plaintext = append(plaintext, bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	env.Ciphertext = make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, env.IV).CryptBlocks(env.Ciphertext, plaintext)
+	env.MAC = mac(macKey, env)
+
+	content = model.Ciphertext(base64.StdEncoding.EncodeToString(env.Bytes()))
+	return
+}
+
+// decrypt decrypts a document from its envelope, checking its MAC first.
+func decrypt(password string, content model.Ciphertext) (doc document, err error) {
+	env, err := model.EnvelopeOf(content)
+	if err != nil || env.Version != model.EnvelopeCBC || env.KDF != model.KDFPBKDF2 {
+		err = ErrUnsupportedEnvelope
+		return
+	}
+
+	encKey, macKey := deriveKeys(password, env.Salt, env.Iterations)
+	if !hmac.Equal(mac(macKey, env), env.MAC) {
+		err = ErrDecrypt
+		return
+	}
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return
+	}
+
+	plaintext := make([]byte, len(env.Ciphertext))
+	cipher.NewCBCDecrypter(block, env.IV).CryptBlocks(plaintext, env.Ciphertext)
+
+	// Remove the PKCS #7 padding.
+	padding := int(plaintext[len(plaintext)-1])
+	if padding < 1 || padding > aes.BlockSize {
+		err = ErrDecrypt
+		return
+	}
+
+	err = json.Unmarshal(plaintext[:len(plaintext)-padding], &doc)
+	if err != nil {
+		err = ErrDecrypt
+	}
+
+	return
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error is an error response from the server.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+// Error describes the error response.
+func (err *Error) Error() string {
+	return fmt.Sprintf("cryptopad: %v (%v)", err.Message, err.StatusCode)
+}
+
+// Is matches errors with the same status code, so errors.Is(err, client.ErrNotFound) can be used.
+func (err *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.StatusCode == err.StatusCode
+}
+
+var (
+	ErrBadRequest         = &Error{StatusCode: http.StatusBadRequest, Message: "request is invalid"}
+	ErrForbidden          = &Error{StatusCode: http.StatusForbidden, Message: "proof is incorrect"}
+	ErrNotFound           = &Error{StatusCode: http.StatusNotFound, Message: "pad does not exist"}
+	ErrConflict           = &Error{StatusCode: http.StatusConflict, Message: "pad was changed by another request"}
+	ErrGone               = &Error{StatusCode: http.StatusGone, Message: "pad has been burnt"}
+	ErrPreconditionFailed = &Error{StatusCode: http.StatusPreconditionFailed, Message: "pad has been updated since it was loaded"}
+
+	// ErrUnavailable is returned when creating a pad with an ID which is already used or reserved.
+	ErrUnavailable = errors.New("cryptopad: pad id is not available")
+
+	// ErrDecrypt is returned when a pad can't be decrypted, because the password is wrong or the content is corrupt.
+	ErrDecrypt = errors.New("cryptopad: password is incorrect or the pad is corrupt")
+
+	// ErrAttachments is returned when rotating the key of a pad with attachments, which this client can't re-encrypt.
+	ErrAttachments = errors.New("cryptopad: pads with attachments can't have their key rotated by this client")
+
+	// ErrUnsupportedEnvelope is returned when a pad was encrypted with a scheme this client doesn't implement.
+	ErrUnsupportedEnvelope = errors.New("cryptopad: pad is not encrypted with a supported scheme")
+)
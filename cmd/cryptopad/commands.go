@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/VolticFroogo/cryptopad-server/client"
+)
+
+var (
+	errPasswordMismatch = errors.New("passwords do not match")
+	errEditConflict     = errors.New("pad was changed while it was being edited, edit it again")
+)
+
+// create creates a pad, with its text from stdin, or from $EDITOR if stdin is a terminal.
+func create(c *client.Client, id string) (err error) {
+	var text string
+	if isTerminal(os.Stdin) {
+		text, err = editText("")
+	} else {
+		var data []byte
+		data, err = ioutil.ReadAll(os.Stdin)
+		text = string(data)
+	}
+
+	if err != nil {
+		return
+	}
+
+	password, err := newPassword(passwordEnv, "Password: ")
+	if err != nil {
+		return
+	}
+
+	_, err = c.Create(id, password, text)
+	return
+}
+
+// cat prints the text of a pad.
+func cat(c *client.Client, id string) (err error) {
+	pad, _, err := load(c, id)
+	if err != nil {
+		return
+	}
+
+	_, err = fmt.Print(pad.Text)
+	return
+}
+
+// edit edits the text of a pad with $EDITOR, saving it if it changed.
+func edit(c *client.Client, id string) (err error) {
+	pad, password, err := load(c, id)
+	if err != nil {
+		return
+	}
+
+	text, err := editText(pad.Text)
+	if err != nil || text == pad.Text {
+		return
+	}
+
+	pad.Text = text
+
+	err = c.Update(pad, password)
+	if errors.Is(err, client.ErrPreconditionFailed) {
+		err = errEditConflict
+	}
+
+	return
+}
+
+// rotateKey encrypts a pad with a new password, and replaces its proof.
+func rotateKey(c *client.Client, id string) (err error) {
+	pad, oldPassword, err := load(c, id)
+	if err != nil {
+		return
+	}
+
+	password, err := newPassword(newPasswordEnv, "New password: ")
+	if err != nil {
+		return
+	}
+
+	return c.RotateKey(pad, oldPassword, password)
+}
+
+// remove deletes a pad, which must be decrypted first to get its proof.
+func remove(c *client.Client, id string) (err error) {
+	pad, _, err := load(c, id)
+	if err != nil {
+		return
+	}
+
+	return c.Delete(pad)
+}
+
+// load asks for a pad's password, and loads it.
+func load(c *client.Client, id string) (pad *client.Pad, password string, err error) {
+	password, err = readPassword(passwordEnv, "Password: ")
+	if err != nil {
+		return
+	}
+
+	pad, err = c.Load(id, password)
+	return
+}
+
+// newPassword asks for a new password twice, to catch typos, unless it is set in an environment variable.
+func newPassword(env, prompt string) (password string, err error) {
+	if password = os.Getenv(env); password != "" {
+		return
+	}
+
+	password, err = readPassword(env, prompt)
+	if err != nil {
+		return
+	}
+
+	confirm, err := readPassword(env, "Confirm "+prompt)
+	if err != nil {
+		return
+	}
+
+	if password != confirm {
+		err = errPasswordMismatch
+	}
+
+	return
+}
@@ -0,0 +1,73 @@
+// Command cryptopad creates, reads and edits pads from the command line.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/VolticFroogo/cryptopad-server/client"
+)
+
+const (
+	// urlEnv, passwordEnv and newPasswordEnv are environment variables scripts can use instead of flags and prompts.
+	urlEnv         = "CRYPTOPAD_URL"
+	passwordEnv    = "CRYPTOPAD_PASSWORD"
+	newPasswordEnv = "CRYPTOPAD_NEW_PASSWORD"
+
+	defaultURL = "http://localhost"
+)
+
+// commands are the subcommands, which each act on the pad with an ID.
+var commands = map[string]func(c *client.Client, id string) error{
+	"create":     create,
+	"cat":        cat,
+	"edit":       edit,
+	"rotate-key": rotateKey,
+	"rm":         remove,
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: cryptopad [-url url] command id
+
+Commands:
+  create      create a pad, with its text from stdin or $EDITOR
+  cat         print the text of a pad
+  edit        edit the text of a pad with $EDITOR
+  rotate-key  encrypt a pad with a new password, and replace its proof
+  rm          delete a pad
+
+Passwords are read from the terminal, or $%v and $%v.
+
+Flags:
+`, passwordEnv, newPasswordEnv)
+	flag.PrintDefaults()
+}
+
+func main() {
+	url := os.Getenv(urlEnv)
+	if url == "" {
+		url = defaultURL
+	}
+
+	flag.StringVar(&url, "url", url, "the server's address, or $"+urlEnv)
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	command, ok := commands[flag.Arg(0)]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	err := command(client.New(url), flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/VolticFroogo/cryptopad-server/api/v1"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/model"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+	"github.com/VolticFroogo/cryptopad-server/client"
+	"github.com/VolticFroogo/cryptopad-server/db"
+	"github.com/gorilla/mux"
+)
+
+const (
+	dbCfgDir  = "../../configs/db_test.ini"
+	padCfgDir = "../../configs/pad_test.ini"
+)
+
+func TestCryptopad(t *testing.T) {
+	// Initialise the DB.
+	err := db.Init(dbCfgDir)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	// Select the pad store for the database.
+	err = pad.Init(padCfgDir)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	r := mux.NewRouter()
+	r.StrictSlash(true)
+	v1.Handle(r)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	c := client.New(server.URL)
+	c.Iterations = model.MinPBKDF2Iterations
+
+	// Passwords come from the environment, as there is no terminal to prompt on.
+	os.Setenv(passwordEnv, "PASSWORD")
+	defer os.Unsetenv(passwordEnv)
+
+	// Run all command related tests.
+	createPiped(t, c)
+	catPad(t, c)
+	editPad(t, c)
+	editUnchanged(t, c)
+	rotateKeyPad(t, c)
+	removePad(t, c)
+}
+
+// withStdin runs a function with text as stdin.
+func withStdin(t *testing.T, text string, f func()) {
+	file, err := ioutil.TempFile("", "cryptopad-stdin-*")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	_, err = file.WriteString(text)
+	if err == nil {
+		_, err = file.Seek(0, 0)
+	}
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	stdin := os.Stdin
+	os.Stdin = file
+	defer func() { os.Stdin = stdin }()
+
+	f()
+}
+
+// withStdout runs a function, returning what it printed to stdout.
+func withStdout(t *testing.T, f func()) string {
+	file, err := ioutil.TempFile("", "cryptopad-stdout-*")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	stdout := os.Stdout
+	os.Stdout = file
+	f()
+	os.Stdout = stdout
+
+	data, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return string(data)
+}
+
+// withEditor runs a function with $EDITOR set to a command.
+func withEditor(editor string, f func()) {
+	old, ok := os.LookupEnv("EDITOR")
+	os.Setenv("EDITOR", editor)
+
+	defer func() {
+		if ok {
+			os.Setenv("EDITOR", old)
+		} else {
+			os.Unsetenv("EDITOR")
+		}
+	}()
+
+	f()
+}
+
+// createPiped tests creating a pad with its text piped in.
+func createPiped(t *testing.T, c *client.Client) {
+	var err error
+	withStdin(t, "SECRET TEXT", func() {
+		err = create(c, "cli-pad")
+	})
+
+	if err != nil {
+		t.Errorf("create: could not create pad (%v)", err)
+		return
+	}
+
+	loaded, err := c.Load("cli-pad", "PASSWORD")
+	if err != nil {
+		t.Errorf("create: could not load pad (%v)", err)
+		return
+	}
+
+	if loaded.Text != "SECRET TEXT" {
+		t.Errorf("create: pad has the wrong text (%v)", loaded.Text)
+		return
+	}
+
+	t.Logf("create: success")
+}
+
+// catPad tests printing the text of a pad.
+func catPad(t *testing.T, c *client.Client) {
+	var err error
+	output := withStdout(t, func() {
+		err = cat(c, "cli-pad")
+	})
+
+	if err != nil {
+		t.Errorf("cat: could not print pad (%v)", err)
+		return
+	}
+
+	if output != "SECRET TEXT" {
+		t.Errorf("cat: printed the wrong text (%v)", output)
+		return
+	}
+
+	t.Logf("cat: success")
+}
+
+// editPad tests editing a pad's text with $EDITOR.
+func editPad(t *testing.T, c *client.Client) {
+	var err error
+	withEditor("sed -i s/SECRET/EDITED/", func() {
+		err = edit(c, "cli-pad")
+	})
+
+	if err != nil {
+		t.Errorf("edit: could not edit pad (%v)", err)
+		return
+	}
+
+	loaded, err := c.Load("cli-pad", "PASSWORD")
+	if err != nil {
+		t.Errorf("edit: could not load pad (%v)", err)
+		return
+	}
+
+	if loaded.Text != "EDITED TEXT" || loaded.Revision != 2 {
+		t.Errorf("edit: pad wasn't saved (%v, %v)", loaded.Text, loaded.Revision)
+		return
+	}
+
+	t.Logf("edit: success")
+}
+
+// editUnchanged tests a pad isn't saved if its text wasn't changed.
+func editUnchanged(t *testing.T, c *client.Client) {
+	var err error
+	withEditor("true", func() {
+		err = edit(c, "cli-pad")
+	})
+
+	if err != nil {
+		t.Errorf("edit unchanged: could not edit pad (%v)", err)
+		return
+	}
+
+	loaded, err := c.Load("cli-pad", "PASSWORD")
+	if err != nil {
+		t.Errorf("edit unchanged: could not load pad (%v)", err)
+		return
+	}
+
+	if loaded.Revision != 2 {
+		t.Errorf("edit unchanged: pad was saved without changes (%v)", loaded.Revision)
+		return
+	}
+
+	t.Logf("edit unchanged: success")
+}
+
+// rotateKeyPad tests encrypting a pad with a new password.
+func rotateKeyPad(t *testing.T, c *client.Client) {
+	os.Setenv(newPasswordEnv, "NEW-PASSWORD")
+	defer os.Unsetenv(newPasswordEnv)
+
+	err := rotateKey(c, "cli-pad")
+	if err != nil {
+		t.Errorf("rotate key: could not rotate pad (%v)", err)
+		return
+	}
+
+	_, err = c.Load("cli-pad", "PASSWORD")
+	if err != client.ErrDecrypt {
+		t.Errorf("rotate key: pad still decrypts with the old password (%v)", err)
+		return
+	}
+
+	loaded, err := c.Load("cli-pad", "NEW-PASSWORD")
+	if err != nil {
+		t.Errorf("rotate key: could not load pad with the new password (%v)", err)
+		return
+	}
+
+	if loaded.Text != "EDITED TEXT" {
+		t.Errorf("rotate key: pad has the wrong text (%v)", loaded.Text)
+		return
+	}
+
+	t.Logf("rotate key: success")
+}
+
+// removePad tests deleting a pad.
+func removePad(t *testing.T, c *client.Client) {
+	os.Setenv(passwordEnv, "NEW-PASSWORD")
+
+	err := remove(c, "cli-pad")
+	if err != nil {
+		t.Errorf("rm: could not delete pad (%v)", err)
+		return
+	}
+
+	_, err = c.Load("cli-pad", "NEW-PASSWORD")
+	if !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("rm: pad still exists (%v)", err)
+		return
+	}
+
+	t.Logf("rm: success")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"golang.org/x/term"
+)
+
+const (
+	defaultEditor = "vi"
+)
+
+var (
+	errNoTerminal = errors.New("no terminal to prompt on, set passwords in the environment instead")
+)
+
+// isTerminal checks if a file is a terminal.
+func isTerminal(file *os.File) bool {
+	return term.IsTerminal(int(file.Fd()))
+}
+
+// terminal opens the controlling terminal, so prompts still work when stdin and stdout are redirected.
+func terminal() (*os.File, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err == nil {
+		return tty, nil
+	}
+
+	// Not every system has /dev/tty, but stdin may still be the terminal.
+	if isTerminal(os.Stdin) {
+		return os.Stdin, nil
+	}
+
+	return nil, errNoTerminal
+}
+
+// readPassword gets a password from an environment variable, or by prompting for it on the terminal without echoing it.
+func readPassword(env, prompt string) (password string, err error) {
+	if password = os.Getenv(env); password != "" {
+		return
+	}
+
+	tty, err := terminal()
+	if err != nil {
+		return
+	}
+
+	if tty != os.Stdin {
+		defer tty.Close()
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	data, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(os.Stderr)
+
+	password = string(data)
+	return
+}
+
+// editText opens text in $EDITOR, returning the text once the editor exits.
+// The decrypted text is written to a temporary file only the user can read, which is removed afterwards.
+func editText(text string) (edited string, err error) {
+	file, err := ioutil.TempFile("", "cryptopad-*.txt")
+	if err != nil {
+		return
+	}
+
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString(text)
+	file.Close()
+	if err != nil {
+		return
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = defaultEditor
+	}
+
+	// The editor is run through the shell, as $EDITOR often has arguments.
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", file.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	// Interactive editors need the terminal, even when stdin and stdout are redirected.
+	if tty, err := terminal(); err == nil {
+		if tty != os.Stdin {
+			defer tty.Close()
+		}
+
+		cmd.Stdin, cmd.Stdout = tty, tty
+	}
+
+	err = cmd.Run()
+	if err != nil {
+		return
+	}
+
+	data, err := ioutil.ReadFile(file.Name())
+	edited = string(data)
+	return
+}
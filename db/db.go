@@ -8,6 +8,18 @@ import (
 	"github.com/VolticFroogo/config"
 	"github.com/gchaincl/dotsql"
 	_ "github.com/go-sql-driver/mysql" // MySQL Driver.
+	_ "github.com/mattn/go-sqlite3"    // SQLite Driver.
+)
+
+const (
+	// DriverMySQL stores pads in a MySQL database.
+	DriverMySQL = "mysql"
+
+	// DriverSQLite stores pads in an embedded SQLite database file.
+	DriverSQLite = "sqlite3"
+
+	// DriverMemory stores pads in memory, they are lost when the server stops.
+	DriverMemory = "memory"
 )
 
 var (
@@ -16,11 +28,14 @@ var (
 
 	// Dot is all of the loaded queries.
 	Dot *dotsql.DotSql
+
+	// Driver is the driver the database was initialised with.
+	Driver string
 )
 
 // Config is the config structure.
 type Config struct {
-	Name, Password, Protocol, Location, Database, QueriesDirectory string
+	Driver, Name, Password, Protocol, Location, Database, Path, QueriesDirectory string
 }
 
 // Init initialises the database.
@@ -32,14 +47,39 @@ func Init(configDirectory string) (err error) {
 		return
 	}
 
-	// Log that we are connecting to the database.
-	log.Print("Connecting to database.")
+	// Default to MySQL for configs written before drivers were selectable.
+	Driver = cfg.Driver
+	if Driver == "" {
+		Driver = DriverMySQL
+	}
+
+	switch Driver {
+	case DriverMySQL:
+		// Log that we are connecting to the database.
+		log.Print("Connecting to database.")
+
+		// Create the connection string from the config.
+		connection := fmt.Sprintf("%v:%v@%v(%v)/%v", cfg.Name, cfg.Password, cfg.Protocol, cfg.Location, cfg.Database)
 
-	// Create the connection string from the config.
-	connection := fmt.Sprintf("%v:%v@%v(%v)/%v", cfg.Name, cfg.Password, cfg.Protocol, cfg.Location, cfg.Database)
+		// Open the SQL connection.
+		SQL, err = sql.Open(Driver, connection)
+	case DriverSQLite:
+		log.Printf("Opening SQLite database %v.", cfg.Path)
+
+		SQL, err = sql.Open(Driver, cfg.Path)
+		if err != nil {
+			return
+		}
+
+		// SQLite only allows a single writer, so share one connection.
+		SQL.SetMaxOpenConns(1)
+	case DriverMemory:
+		log.Print("Using in-memory database.")
+		return
+	default:
+		err = fmt.Errorf("unknown database driver %v", Driver)
+	}
 
-	// Open the SQL connection.
-	SQL, err = sql.Open("mysql", connection)
 	if err != nil {
 		return
 	}
@@ -48,3 +88,12 @@ func Init(configDirectory string) (err error) {
 	Dot, err = dotsql.LoadFromFile(cfg.QueriesDirectory)
 	return
 }
+
+// Close closes the database, waiting for any queries which have started to finish.
+func Close() error {
+	if SQL == nil {
+		return nil
+	}
+
+	return SQL.Close()
+}
@@ -1,11 +1,18 @@
 package handle
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/VolticFroogo/config"
 	v1 "github.com/VolticFroogo/cryptopad-server/api/v1"
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
+	"github.com/VolticFroogo/cryptopad-server/ratelimit"
 	"github.com/gorilla/mux"
 )
 
@@ -17,9 +24,16 @@ const (
 type Config struct {
 	Port, Certificate, Key string
 	SSL                    bool
+	RateLimit              ratelimit.Config
+
+	// Timeouts are in seconds, and 0 disables them.
+	// ReadHeaderTimeout and ReadTimeout are how long a client has to send a request's headers, and all of it.
+	// WriteTimeout is how long a response may take, and IdleTimeout how long a kept alive connection waits for the next request.
+	// ShutdownTimeout is how long requests which have already started have to finish when the server is stopped.
+	ReadHeaderTimeout, ReadTimeout, WriteTimeout, IdleTimeout, ShutdownTimeout int
 }
 
-// Start begins listening for all incoming requests.
+// Start begins listening for all incoming requests, until the server is stopped by SIGINT or SIGTERM.
 func Start() {
 	// Load the config.
 	cfg := Config{}
@@ -29,6 +43,9 @@ func Start() {
 		return
 	}
 
+	// Limit how quickly clients can make requests.
+	ratelimit.Configure(cfg.RateLimit)
+
 	// Create a new Mux Router with strict slash.
 	r := mux.NewRouter()
 	r.StrictSlash(true)
@@ -42,17 +59,69 @@ func Start() {
 	// Handle all static files with the file server.
 	r.PathPrefix("/").Handler(fileServer)
 
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           r,
+		ReadHeaderTimeout: seconds(cfg.ReadHeaderTimeout),
+		ReadTimeout:       seconds(cfg.ReadTimeout),
+		WriteTimeout:      seconds(cfg.WriteTimeout),
+		IdleTimeout:       seconds(cfg.IdleTimeout),
+	}
+
+	// Subscriptions never finish by themselves, so end them when shutting down.
+	server.RegisterOnShutdown(pad.CloseSubscribers)
+
+	stopped := make(chan struct{})
+	go shutdown(server, seconds(cfg.ShutdownTimeout), stopped)
+
 	if cfg.SSL {
 		// If we are using SSL encryption (HTTPS):
 		log.Printf("Listening for incoming HTTPS requests on port %v.", cfg.Port)
 
 		// Serve TLS using the certificate and key files from the config.
-		http.ListenAndServeTLS(":"+cfg.Port, cfg.Certificate, cfg.Key, r)
+		err = server.ListenAndServeTLS(cfg.Certificate, cfg.Key)
 	} else {
 		// Otherwise:
 		log.Printf("Listening for incoming HTTP requests on port %v.", cfg.Port)
 
 		// Serve plain HTTP responses.
-		http.ListenAndServe(":"+cfg.Port, r)
+		err = server.ListenAndServe()
 	}
+
+	if err != http.ErrServerClosed {
+		log.Print(err)
+		return
+	}
+
+	// Wait for the requests which had already started to finish.
+	<-stopped
+}
+
+// shutdown stops a server once the process is asked to stop, letting requests which have already started finish.
+// The stopped channel is closed once they have, or the timeout has passed.
+func shutdown(server *http.Server, timeout time.Duration, stopped chan struct{}) {
+	defer close(stopped)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+
+	log.Print("Shutting down, waiting for requests to finish.")
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := server.Shutdown(ctx)
+	if err != nil {
+		log.Print(err)
+	}
+}
+
+// seconds converts a number of seconds from the config into a duration.
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
 }
@@ -0,0 +1,223 @@
+package helper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	FormatJSON        = "application/json"
+	FormatCBOR        = "application/cbor"
+	FormatMsgpack     = "application/msgpack"
+	FormatOctetStream = "application/octet-stream"
+)
+
+var (
+	// ErrUnsupportedFormat is returned when decoding a request body of a type which isn't supported.
+	ErrUnsupportedFormat = errors.New("request body must be JSON, CBOR or MessagePack")
+
+	// aliases are other media types clients send for the supported formats.
+	aliases = map[string]string{
+		"text/json":               FormatJSON,
+		"application/x-msgpack":   FormatMsgpack,
+		"application/vnd.msgpack": FormatMsgpack,
+	}
+
+	// Byte strings can be decoded into strings, so binary formats can send ciphertext without encoding it.
+	cborDecMode, _ = cbor.DecOptions{
+		ByteStringToString: cbor.ByteStringToStringAllowed,
+	}.DecMode()
+)
+
+// formatWriter is a response writer which knows the format its responses should be encoded in.
+type formatWriter struct {
+	http.ResponseWriter
+	format string
+}
+
+// Flush sends any buffered data to the client, for streamed responses.
+func (w *formatWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the handler take over the connection, for WebSockets.
+func (w *formatWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	return hijacker.Hijack()
+}
+
+// Unwrap gets the original response writer, for http.ResponseController.
+func (w *formatWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Negotiate is middleware which picks the format of a request's responses from its Accept header.
+// Clients which don't accept any of the formats are sent JSON, as they always have been.
+func Negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := Accepted(r, FormatJSON, FormatCBOR, FormatMsgpack)
+		if format == "" {
+			format = FormatJSON
+		}
+
+		next.ServeHTTP(&formatWriter{w, format}, r)
+	})
+}
+
+// ResponseFormat gets the format negotiated for a response, which is JSON if it wasn't negotiated.
+// Writers wrapped by other middleware are unwrapped to find it.
+func ResponseFormat(w http.ResponseWriter) string {
+	for {
+		switch writer := w.(type) {
+		case *formatWriter:
+			return writer.format
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = writer.Unwrap()
+		default:
+			return FormatJSON
+		}
+	}
+}
+
+// Accepted gets which of the offered media types a request's Accept header prefers, or "" if it accepts none of them.
+// Offers are preferred in order when the client has no preference, so requests without an Accept header get the first.
+func Accepted(r *http.Request, offers ...string) string {
+	header := strings.Join(r.Header.Values("Accept"), ",")
+	if header == "" {
+		return offers[0]
+	}
+
+	type acceptRange struct {
+		mediaType string
+		q         float64
+	}
+
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		ranges = append(ranges, acceptRange{canonical(mediaType), q})
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		// The most specific range matching an offer decides its quality.
+		q, specificity := 0.0, 0
+		for _, rng := range ranges {
+			s := 0
+			switch {
+			case rng.mediaType == offer:
+				s = 3
+			case strings.HasSuffix(rng.mediaType, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(rng.mediaType, "*")):
+				s = 2
+			case rng.mediaType == "*/*":
+				s = 1
+			}
+
+			if s > specificity {
+				q, specificity = rng.q, s
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	return best
+}
+
+// Decode decodes a request's body into v, in the format given by its Content-Type header.
+// Requests without a Content-Type are decoded as JSON.
+func Decode(r *http.Request, v interface{}) error {
+	format := FormatJSON
+	if header := r.Header.Get("Content-Type"); header != "" {
+		mediaType, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			return ErrUnsupportedFormat
+		}
+
+		format = canonical(mediaType)
+	}
+
+	switch format {
+	case FormatJSON:
+		return json.NewDecoder(r.Body).Decode(v)
+	case FormatCBOR:
+		return cborDecMode.NewDecoder(r.Body).Decode(v)
+	case FormatMsgpack:
+		dec := msgpack.NewDecoder(r.Body)
+		dec.SetCustomStructTag("json")
+		return dec.Decode(v)
+	}
+
+	return ErrUnsupportedFormat
+}
+
+// DecodeErrStatus gets the status code for an error from decoding a request's body.
+func DecodeErrStatus(err error) int {
+	if err == ErrUnsupportedFormat {
+		return http.StatusUnsupportedMediaType
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusInternalServerError
+}
+
+// Marshal encodes v in a format, using the same field names as JSON for every format.
+func Marshal(format string, v interface{}) ([]byte, error) {
+	switch format {
+	case FormatCBOR:
+		return cbor.Marshal(v)
+	case FormatMsgpack:
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		enc.SetCustomStructTag("json")
+		enc.UseCompactInts(true)
+
+		err := enc.Encode(v)
+		return buf.Bytes(), err
+	}
+
+	return json.Marshal(v)
+}
+
+// canonical gets the media type a format is known by, from any of its aliases.
+func canonical(mediaType string) string {
+	if alias, ok := aliases[mediaType]; ok {
+		return alias
+	}
+
+	return mediaType
+}
@@ -1,33 +1,36 @@
 package helper
 
 import (
-	"encoding/json"
 	"net/http"
 )
 
-// ErrorResponse is the type used for error JSON responses.
+// ErrorResponse is the type used for error responses.
 type ErrorResponse struct {
 	Error string
 }
 
-// JSONResponse sends a client a JSON response.
-func JSONResponse(data interface{}, status int, w http.ResponseWriter) (err error) {
-	// Set the status header of the response.
-	w.WriteHeader(status)
+// Response sends a client a response, encoded in the format negotiated for the request.
+func Response(data interface{}, status int, w http.ResponseWriter) (err error) {
+	format := ResponseFormat(w)
 
-	dataJSON, err := json.Marshal(data) // Encode response into JSON.
+	body, err := Marshal(format, data) // Encode the response before sending the status, in case it fails.
 	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	w.Write(dataJSON) // Write JSON data to response writer.
+	// Set the type and status headers of the response.
+	w.Header().Set("Content-Type", format)
+	w.WriteHeader(status)
+
+	_, err = w.Write(body) // Write encoded data to response writer.
 	return
 }
 
-// ThrowErr is used for throwing errors via a JSON response.
+// ThrowErr is used for throwing errors via a response.
 func ThrowErr(err error, status int, w http.ResponseWriter) {
-	// Send the error as a JSON response.
-	JSONResponse(ErrorResponse{
+	// Send the error as a response.
+	Response(ErrorResponse{
 		Error: err.Error(),
 	}, status, w)
 }
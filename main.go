@@ -3,12 +3,14 @@ package main
 import (
 	"log"
 
+	"github.com/VolticFroogo/cryptopad-server/api/v1/pad"
 	"github.com/VolticFroogo/cryptopad-server/db"
 	"github.com/VolticFroogo/cryptopad-server/handle"
 )
 
 const (
-	dbCfgDir = "configs/db.ini"
+	dbCfgDir  = "configs/db.ini"
+	padCfgDir = "configs/pad.ini"
 )
 
 func main() {
@@ -19,6 +21,22 @@ func main() {
 		return
 	}
 
-	// Start handling incoming requests.
+	// Select the pad store for the database.
+	err = pad.Init(padCfgDir)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	// Remove expired pads in the background.
+	go pad.Sweep()
+
+	// Start handling incoming requests, until the server is shut down.
 	handle.Start()
+
+	// Close the database once the requests using it have finished.
+	err = db.Close()
+	if err != nil {
+		log.Print(err)
+	}
 }
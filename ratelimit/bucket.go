@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// sweepInterval is how often buckets which have refilled are forgotten, so idle clients don't use memory.
+	sweepInterval = time.Minute
+)
+
+// Rate is how many requests can be made at once, and how quickly they can be made again afterwards.
+type Rate struct {
+	Burst     int
+	PerMinute float64
+}
+
+// enabled checks if a rate limits anything. Rates without a burst or refill don't.
+func (rate Rate) enabled() bool {
+	return rate.Burst > 0 && rate.PerMinute > 0
+}
+
+// bucket is a token bucket, which holds up to the burst of tokens and refills at the rate.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// limiter is a set of token buckets with the same rate, each for a different key.
+type limiter struct {
+	rate    Rate
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// newLimiter creates a limiter for a rate, or nil if the rate doesn't limit anything.
+func newLimiter(rate Rate) *limiter {
+	if !rate.enabled() {
+		return nil
+	}
+
+	return &limiter{
+		rate:    rate,
+		buckets: make(map[string]*bucket),
+		swept:   time.Now(),
+	}
+}
+
+// take takes a token from a key's bucket.
+// If the bucket is empty, nothing is taken and how long until it has a token again is returned instead.
+func (l *limiter) take(key string) (ok bool, retry time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b := l.fill(key, time.Now())
+	if b.tokens < 1 {
+		return false, l.wait(b)
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// check checks a key's bucket has a token, without taking it.
+func (l *limiter) check(key string) (ok bool, retry time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b := l.fill(key, time.Now())
+	if b.tokens < 1 {
+		return false, l.wait(b)
+	}
+
+	return true, 0
+}
+
+// spend takes a token from a key's bucket even if it is empty, so concurrent requests still pay for what they did.
+// Buckets can't go below -burst, so keys are never limited for more than twice as long as a full refill.
+func (l *limiter) spend(key string) {
+	if l == nil {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b := l.fill(key, time.Now())
+	b.tokens = math.Max(b.tokens-1, -float64(l.rate.Burst))
+}
+
+// fill gets a key's bucket, refilled for the time since it was last used.
+// This must be called with the mutex locked.
+func (l *limiter) fill(key string, now time.Time) *bucket {
+	if now.Sub(l.swept) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{
+			tokens:  float64(l.rate.Burst),
+			updated: now,
+		}
+
+		l.buckets[key] = b
+		return b
+	}
+
+	b.tokens = math.Min(b.tokens+now.Sub(b.updated).Minutes()*l.rate.PerMinute, float64(l.rate.Burst))
+	b.updated = now
+	return b
+}
+
+// sweep forgets buckets which would be full by now, as a new bucket would be the same.
+// This must be called with the mutex locked.
+func (l *limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Minutes()*l.rate.PerMinute >= float64(l.rate.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.swept = now
+}
+
+// wait gets how long until a bucket has a token.
+func (l *limiter) wait(b *bucket) time.Duration {
+	return time.Duration((1 - b.tokens) / l.rate.PerMinute * float64(time.Minute))
+}
@@ -0,0 +1,193 @@
+// Package ratelimit limits how quickly clients can make requests, to each pad and overall,
+// and more strictly how often they can fail to prove they may access a pad, so proofs can't be brute-forced.
+package ratelimit
+
+import (
+	"bufio"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VolticFroogo/cryptopad-server/helper"
+	"github.com/gorilla/mux"
+)
+
+// Config is the config structure.
+type Config struct {
+	// IP limits every request from each client.
+	IP Rate
+
+	// Pad limits requests to each pad, from every client.
+	Pad Rate
+
+	// FailedProof limits failed attempts to prove access, from each client and to each pad.
+	FailedProof Rate
+
+	// TrustProxy uses the last address in X-Forwarded-For as the client's, for servers behind a reverse proxy.
+	TrustProxy bool
+}
+
+var (
+	errTooManyRequests = errors.New("too many requests, try again later")
+	errTooManyFailures = errors.New("too many failed attempts, try again later")
+
+	cfg Config
+
+	ips, pads, failures *limiter
+)
+
+// Configure sets the rates requests are limited to. Rates which are left empty don't limit anything.
+func Configure(config Config) {
+	cfg = config
+
+	ips = newLimiter(cfg.IP)
+	pads = newLimiter(cfg.Pad)
+	failures = newLimiter(cfg.FailedProof)
+}
+
+// statusWriter is a response writer which remembers the status sent, and the pad the request was for.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	pad    string
+}
+
+// WriteHeader sends and remembers the status.
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write sends the body, which implies a 200 status if none was sent.
+func (w *statusWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush sends any buffered data to the client, for streamed responses.
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the handler take over the connection, for WebSockets.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	return hijacker.Hijack()
+}
+
+// Unwrap gets the original response writer, for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Limit is middleware which limits requests by the client's IP, and by the pad in the URL if there is one.
+// Requests which fail to prove access (with 403 Forbidden) also count against the stricter failed proof limit.
+func Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := ClientIP(r)
+
+		if ok, retry := ips.take(ip); !ok {
+			tooMany(w, errTooManyRequests, retry)
+			return
+		}
+
+		if ok, retry := failures.check("ip:" + ip); !ok {
+			tooMany(w, errTooManyFailures, retry)
+			return
+		}
+
+		sw := &statusWriter{ResponseWriter: w}
+
+		if id := mux.Vars(r)["id"]; id != "" && !Pad(sw, r, id) {
+			return
+		}
+
+		next.ServeHTTP(sw, r)
+
+		if sw.status == http.StatusForbidden {
+			failures.spend("ip:" + ip)
+
+			if sw.pad != "" {
+				failures.spend("pad:" + sw.pad)
+			}
+		}
+	})
+}
+
+// Pad limits a request to a pad, sending 429 Too Many Requests and returning false if it is over the limit.
+// Handlers whose pad ID is in the body, rather than the URL, call this once they have decoded it.
+func Pad(w http.ResponseWriter, r *http.Request, id string) bool {
+	sw := statusWriterOf(w)
+	if sw == nil || sw.pad != "" {
+		// The request isn't being limited, or was already limited by the pad in its URL.
+		return true
+	}
+
+	sw.pad = id
+
+	if ok, retry := pads.take(id); !ok {
+		tooMany(w, errTooManyRequests, retry)
+		return false
+	}
+
+	if ok, retry := failures.check("pad:" + id); !ok {
+		tooMany(w, errTooManyFailures, retry)
+		return false
+	}
+
+	return true
+}
+
+// statusWriterOf finds the status writer a response writer wraps, or nil if it isn't being limited.
+func statusWriterOf(w http.ResponseWriter) *statusWriter {
+	for {
+		switch writer := w.(type) {
+		case *statusWriter:
+			return writer
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = writer.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// ClientIP gets the IP address a request came from, from X-Forwarded-For if the proxy is trusted.
+func ClientIP(r *http.Request) string {
+	if cfg.TrustProxy {
+		// The proxy appends the address it received the request from, so earlier ones could be forged.
+		forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		if ip := strings.TrimSpace(forwarded[len(forwarded)-1]); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// tooMany sends a 429 Too Many Requests error, telling the client how many seconds to wait before retrying.
+func tooMany(w http.ResponseWriter, err error, retry time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+	helper.ThrowErr(err, http.StatusTooManyRequests, w)
+}
@@ -1,11 +1,200 @@
 -- name: v1-pad-from-id
-SELECT id, content, proof FROM pad WHERE BINARY id=?;
+SELECT id, content, proof, revision, expires_at, burn_after_reading, public_key, read_token, compacted, chunks, size FROM pad WHERE BINARY id=?;
 
 -- name: v1-insert-pad
-INSERT INTO pad (id, content, proof) VALUES (?, ?, ?);
+INSERT INTO pad (id, content, proof, revision, expires_at, burn_after_reading, public_key, read_token, size) VALUES (?, ?, ?, 1, ?, ?, ?, ?, ?);
 
 -- name: v1-update-pad
-UPDATE pad SET content=?, proof=? WHERE id=?;
+UPDATE pad SET content=?, proof=?, expires_at=?, burn_after_reading=?, public_key=?, read_token=?, compacted=?, chunks=0, size=?, revision=revision+1 WHERE id=? AND revision=?;
 
 -- name: v1-remove-pad
 DELETE FROM pad WHERE id=?;
+
+-- name: v1-revisions-from-id
+SELECT revision, created FROM pad_revision WHERE BINARY pad_id=? ORDER BY revision;
+
+-- name: v1-revision-from-id
+SELECT pad_id, content, revision FROM pad_revision WHERE BINARY pad_id=? AND revision=?;
+
+-- name: v1-insert-revision
+INSERT INTO pad_revision (pad_id, revision, content, created) VALUES (?, ?, ?, ?);
+
+-- name: v1-prune-revisions
+DELETE FROM pad_revision WHERE pad_id=? AND revision<=?;
+
+-- name: v1-remove-revisions
+DELETE FROM pad_revision WHERE pad_id=?;
+
+-- name: v1-remove-expired-revisions
+DELETE FROM pad_revision WHERE pad_id IN (SELECT id FROM pad WHERE expires_at<>0 AND expires_at<=?);
+
+-- name: v1-expired-pads
+SELECT id FROM pad WHERE expires_at<>0 AND expires_at<=?;
+
+-- name: v1-remove-expired-pads
+DELETE FROM pad WHERE expires_at<>0 AND expires_at<=?;
+
+-- name: v1-remove-expired-burnt
+DELETE FROM pad_burnt WHERE expires_at<=?;
+
+-- name: v1-burn-pad
+DELETE FROM pad WHERE id=? AND revision=?;
+
+-- name: v1-insert-burnt
+REPLACE INTO pad_burnt (id, expires_at) VALUES (?, ?);
+
+-- name: v1-burnt-from-id
+SELECT COUNT(*) FROM pad_burnt WHERE BINARY id=?;
+
+-- name: v1-remove-burnt
+DELETE FROM pad_burnt WHERE id=?;
+
+-- name: v1-remove-expired-editors
+DELETE FROM pad_editor WHERE pad_id IN (SELECT id FROM pad WHERE expires_at<>0 AND expires_at<=?);
+
+-- name: v1-editors-from-id
+SELECT name, proof FROM pad_editor WHERE BINARY pad_id=? ORDER BY name;
+
+-- name: v1-editor-from-name
+SELECT name, proof FROM pad_editor WHERE BINARY pad_id=? AND BINARY name=?;
+
+-- name: v1-put-editor
+REPLACE INTO pad_editor (pad_id, name, proof) VALUES (?, ?, ?);
+
+-- name: v1-remove-editor
+DELETE FROM pad_editor WHERE pad_id=? AND name=?;
+
+-- name: v1-remove-editors
+DELETE FROM pad_editor WHERE pad_id=?;
+
+-- name: v1-ops-from-id
+SELECT sequence, content, created FROM pad_op WHERE BINARY pad_id=? AND sequence>? ORDER BY sequence;
+
+-- name: v1-op-sequence-from-id
+SELECT GREATEST(compacted, COALESCE((SELECT MAX(sequence) FROM pad_op WHERE BINARY pad_id=?), 0)) FROM pad WHERE BINARY id=?;
+
+-- name: v1-insert-op
+INSERT INTO pad_op (pad_id, sequence, content, created) VALUES (?, ?, ?, ?);
+
+-- name: v1-prune-ops
+DELETE FROM pad_op WHERE pad_id=? AND sequence<=?;
+
+-- name: v1-remove-ops
+DELETE FROM pad_op WHERE pad_id=?;
+
+-- name: v1-remove-expired-ops
+DELETE FROM pad_op WHERE pad_id IN (SELECT id FROM pad WHERE expires_at<>0 AND expires_at<=?);
+
+-- name: v1-insert-upload
+INSERT INTO pad_upload (id, pad_id, revision, chunks, size, expires_at, hashes) SELECT ?, ?, ?, ?, ?, ?, ? FROM DUAL WHERE ?<=0 OR (SELECT COUNT(*) FROM pad_upload WHERE BINARY pad_id=? AND expires_at>?)<?;
+
+-- name: v1-upload-from-id
+SELECT id, pad_id, revision, chunks, size, expires_at, hashes FROM pad_upload WHERE BINARY id=?;
+
+-- name: v1-put-upload-chunk
+REPLACE INTO pad_upload_chunk (upload_id, idx, data, hash) SELECT ?, ?, ?, ? FROM pad_upload WHERE BINARY id=? AND (SELECT COALESCE(SUM(LENGTH(data)), 0) FROM pad_upload_chunk WHERE BINARY upload_id=? AND idx<>?)+?<=size;
+
+-- name: v1-upload-chunk-size
+SELECT LENGTH(data) FROM pad_upload_chunk WHERE BINARY upload_id=? AND idx=?;
+
+-- name: v1-upload-chunks-from-id
+SELECT idx, LENGTH(data), hash FROM pad_upload_chunk WHERE BINARY upload_id=? ORDER BY idx;
+
+-- name: v1-commit-upload-pad
+UPDATE pad SET content='', chunks=?, size=?, revision=revision+1 WHERE id=? AND revision=?;
+
+-- name: v1-commit-upload-chunks
+INSERT INTO pad_chunk (pad_id, idx, data) SELECT ?, idx, data FROM pad_upload_chunk WHERE upload_id=?;
+
+-- name: v1-remove-upload-chunks
+DELETE FROM pad_upload_chunk WHERE upload_id=?;
+
+-- name: v1-remove-upload
+DELETE FROM pad_upload WHERE id=?;
+
+-- name: v1-chunk-from-id
+SELECT data FROM pad_chunk WHERE BINARY pad_id=? AND idx=?;
+
+-- name: v1-remove-chunks
+DELETE FROM pad_chunk WHERE pad_id=?;
+
+-- name: v1-remove-pad-upload-chunks
+DELETE FROM pad_upload_chunk WHERE upload_id IN (SELECT id FROM pad_upload WHERE pad_id=?);
+
+-- name: v1-remove-pad-uploads
+DELETE FROM pad_upload WHERE pad_id=?;
+
+-- name: v1-remove-expired-chunks
+DELETE FROM pad_chunk WHERE pad_id IN (SELECT id FROM pad WHERE expires_at<>0 AND expires_at<=?);
+
+-- name: v1-remove-expired-upload-chunks
+DELETE FROM pad_upload_chunk WHERE upload_id IN (SELECT id FROM pad_upload WHERE expires_at<=?);
+
+-- name: v1-remove-expired-uploads
+DELETE FROM pad_upload WHERE expires_at<=? AND NOT EXISTS (SELECT 1 FROM pad WHERE pad.id=pad_upload.pad_id AND pad.revision=pad_upload.revision);
+
+-- name: v1-attachments-from-id
+SELECT id, size, created FROM pad_attachment WHERE BINARY pad_id=? ORDER BY created, id;
+
+-- name: v1-attachment-from-id
+SELECT id, size, created, data FROM pad_attachment WHERE BINARY pad_id=? AND BINARY id=?;
+
+-- name: v1-insert-attachment
+INSERT INTO pad_attachment (pad_id, id, size, created, data) SELECT ?, ?, ?, ?, ? FROM DUAL WHERE (SELECT COUNT(*) FROM pad_attachment WHERE BINARY pad_id=?)<?;
+
+-- name: v1-remove-attachment
+DELETE FROM pad_attachment WHERE pad_id=? AND id=?;
+
+-- name: v1-remove-attachments
+DELETE FROM pad_attachment WHERE pad_id=?;
+
+-- name: v1-remove-expired-attachments
+DELETE FROM pad_attachment WHERE pad_id IN (SELECT id FROM pad WHERE expires_at<>0 AND expires_at<=?);
+
+-- name: v1-removed-attachment
+SELECT revision FROM pad_removed_attachment WHERE BINARY pad_id=? AND BINARY id=?;
+
+-- name: v1-insert-removed-attachment
+REPLACE INTO pad_removed_attachment (pad_id, id, revision) SELECT id, ?, revision FROM pad WHERE id=?;
+
+-- name: v1-remove-removed-attachments
+DELETE FROM pad_removed_attachment WHERE pad_id=?;
+
+-- name: v1-remove-stale-removed-attachments
+DELETE FROM pad_removed_attachment WHERE NOT EXISTS (SELECT 1 FROM pad WHERE pad.id=pad_removed_attachment.pad_id AND pad.revision=pad_removed_attachment.revision);
+
+-- name: v1-update-revision
+UPDATE pad_revision SET content=? WHERE pad_id=? AND revision=?;
+
+-- name: v1-count-revisions
+SELECT COUNT(*) FROM pad_revision WHERE BINARY pad_id=?;
+
+-- name: v1-update-attachment
+UPDATE pad_attachment SET size=?, data=? WHERE pad_id=? AND id=?;
+
+-- name: v1-count-attachments
+SELECT COUNT(*) FROM pad_attachment WHERE BINARY pad_id=?;
+
+-- name: v1-lockout-from-id
+SELECT failures, locked_until FROM pad_lockout WHERE BINARY pad_id=?;
+
+-- name: v1-add-failure
+INSERT INTO pad_lockout (pad_id, failures, locked_until) VALUES (?, 1, 0) ON DUPLICATE KEY UPDATE failures=failures+1;
+
+-- name: v1-lock-pad
+UPDATE pad_lockout SET locked_until=? WHERE pad_id=?;
+
+-- name: v1-remove-lockout
+DELETE FROM pad_lockout WHERE pad_id=?;
+
+-- name: v1-remove-expired-lockouts
+DELETE FROM pad_lockout WHERE pad_id IN (SELECT id FROM pad WHERE expires_at<>0 AND expires_at<=?);
+
+-- name: v1-pad-usage
+SELECT COUNT(*), COALESCE(SUM(size), 0) FROM pad;
+
+-- name: v1-attachment-usage
+SELECT COALESCE(SUM(size), 0) FROM pad_attachment;
+
+-- name: v1-upload-usage
+SELECT COALESCE(SUM(LENGTH(data)), 0) FROM pad_upload_chunk;
@@ -0,0 +1,300 @@
+-- name: v1-create-pad-table
+CREATE TABLE IF NOT EXISTS pad (
+    id VARCHAR(16) NOT NULL PRIMARY KEY,
+    content BLOB NOT NULL,
+    proof VARCHAR(128) NOT NULL,
+    revision INTEGER NOT NULL DEFAULT 1,
+    expires_at INTEGER NOT NULL DEFAULT 0,
+    burn_after_reading BOOLEAN NOT NULL DEFAULT 0,
+    public_key VARCHAR(44) NOT NULL DEFAULT '',
+    read_token VARCHAR(64) NOT NULL DEFAULT '',
+    compacted INTEGER NOT NULL DEFAULT 0,
+    chunks INTEGER NOT NULL DEFAULT 0,
+    size INTEGER NOT NULL DEFAULT 0
+);
+
+-- name: v1-create-revision-table
+CREATE TABLE IF NOT EXISTS pad_revision (
+    pad_id VARCHAR(16) NOT NULL,
+    revision INTEGER NOT NULL,
+    content BLOB NOT NULL,
+    created INTEGER NOT NULL,
+    PRIMARY KEY (pad_id, revision)
+);
+
+-- name: v1-create-burnt-table
+CREATE TABLE IF NOT EXISTS pad_burnt (
+    id VARCHAR(16) NOT NULL PRIMARY KEY,
+    expires_at INTEGER NOT NULL
+);
+
+-- name: v1-create-editor-table
+CREATE TABLE IF NOT EXISTS pad_editor (
+    pad_id VARCHAR(16) NOT NULL,
+    name VARCHAR(32) NOT NULL,
+    proof VARCHAR(128) NOT NULL,
+    PRIMARY KEY (pad_id, name)
+);
+
+-- name: v1-create-op-table
+CREATE TABLE IF NOT EXISTS pad_op (
+    pad_id VARCHAR(16) NOT NULL,
+    sequence INTEGER NOT NULL,
+    content BLOB NOT NULL,
+    created INTEGER NOT NULL,
+    PRIMARY KEY (pad_id, sequence)
+);
+
+-- name: v1-create-upload-table
+CREATE TABLE IF NOT EXISTS pad_upload (
+    id VARCHAR(43) NOT NULL PRIMARY KEY,
+    pad_id VARCHAR(16) NOT NULL,
+    revision INTEGER NOT NULL,
+    chunks INTEGER NOT NULL,
+    size INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL,
+    hashes TEXT NOT NULL DEFAULT ''
+);
+
+-- name: v1-create-upload-chunk-table
+CREATE TABLE IF NOT EXISTS pad_upload_chunk (
+    upload_id VARCHAR(43) NOT NULL,
+    idx INTEGER NOT NULL,
+    data BLOB NOT NULL,
+    hash VARCHAR(64) NOT NULL DEFAULT '',
+    PRIMARY KEY (upload_id, idx)
+);
+
+-- name: v1-create-chunk-table
+CREATE TABLE IF NOT EXISTS pad_chunk (
+    pad_id VARCHAR(16) NOT NULL,
+    idx INTEGER NOT NULL,
+    data BLOB NOT NULL,
+    PRIMARY KEY (pad_id, idx)
+);
+
+-- name: v1-create-attachment-table
+CREATE TABLE IF NOT EXISTS pad_attachment (
+    pad_id VARCHAR(16) NOT NULL,
+    id VARCHAR(22) NOT NULL,
+    size INTEGER NOT NULL,
+    created INTEGER NOT NULL,
+    data BLOB NOT NULL,
+    PRIMARY KEY (pad_id, id)
+);
+
+-- name: v1-create-removed-attachment-table
+CREATE TABLE IF NOT EXISTS pad_removed_attachment (
+    pad_id VARCHAR(16) NOT NULL,
+    id VARCHAR(22) NOT NULL,
+    revision INTEGER NOT NULL,
+    PRIMARY KEY (pad_id, id)
+);
+
+-- name: v1-create-lockout-table
+CREATE TABLE IF NOT EXISTS pad_lockout (
+    pad_id VARCHAR(16) NOT NULL PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    locked_until INTEGER NOT NULL
+);
+
+-- name: v1-pad-from-id
+SELECT id, content, proof, revision, expires_at, burn_after_reading, public_key, read_token, compacted, chunks, size FROM pad WHERE id=?;
+
+-- name: v1-insert-pad
+INSERT INTO pad (id, content, proof, revision, expires_at, burn_after_reading, public_key, read_token, size) VALUES (?, ?, ?, 1, ?, ?, ?, ?, ?);
+
+-- name: v1-update-pad
+UPDATE pad SET content=?, proof=?, expires_at=?, burn_after_reading=?, public_key=?, read_token=?, compacted=?, chunks=0, size=?, revision=revision+1 WHERE id=? AND revision=?;
+
+-- name: v1-remove-pad
+DELETE FROM pad WHERE id=?;
+
+-- name: v1-revisions-from-id
+SELECT revision, created FROM pad_revision WHERE pad_id=? ORDER BY revision;
+
+-- name: v1-revision-from-id
+SELECT pad_id, content, revision FROM pad_revision WHERE pad_id=? AND revision=?;
+
+-- name: v1-insert-revision
+INSERT INTO pad_revision (pad_id, revision, content, created) VALUES (?, ?, ?, ?);
+
+-- name: v1-prune-revisions
+DELETE FROM pad_revision WHERE pad_id=? AND revision<=?;
+
+-- name: v1-remove-revisions
+DELETE FROM pad_revision WHERE pad_id=?;
+
+-- name: v1-remove-expired-revisions
+DELETE FROM pad_revision WHERE pad_id IN (SELECT id FROM pad WHERE expires_at<>0 AND expires_at<=?);
+
+-- name: v1-expired-pads
+SELECT id FROM pad WHERE expires_at<>0 AND expires_at<=?;
+
+-- name: v1-remove-expired-pads
+DELETE FROM pad WHERE expires_at<>0 AND expires_at<=?;
+
+-- name: v1-remove-expired-burnt
+DELETE FROM pad_burnt WHERE expires_at<=?;
+
+-- name: v1-burn-pad
+DELETE FROM pad WHERE id=? AND revision=?;
+
+-- name: v1-insert-burnt
+REPLACE INTO pad_burnt (id, expires_at) VALUES (?, ?);
+
+-- name: v1-burnt-from-id
+SELECT COUNT(*) FROM pad_burnt WHERE id=?;
+
+-- name: v1-remove-burnt
+DELETE FROM pad_burnt WHERE id=?;
+
+-- name: v1-remove-expired-editors
+DELETE FROM pad_editor WHERE pad_id IN (SELECT id FROM pad WHERE expires_at<>0 AND expires_at<=?);
+
+-- name: v1-editors-from-id
+SELECT name, proof FROM pad_editor WHERE pad_id=? ORDER BY name;
+
+-- name: v1-editor-from-name
+SELECT name, proof FROM pad_editor WHERE pad_id=? AND name=?;
+
+-- name: v1-put-editor
+REPLACE INTO pad_editor (pad_id, name, proof) VALUES (?, ?, ?);
+
+-- name: v1-remove-editor
+DELETE FROM pad_editor WHERE pad_id=? AND name=?;
+
+-- name: v1-remove-editors
+DELETE FROM pad_editor WHERE pad_id=?;
+
+-- name: v1-ops-from-id
+SELECT sequence, content, created FROM pad_op WHERE pad_id=? AND sequence>? ORDER BY sequence;
+
+-- name: v1-op-sequence-from-id
+SELECT MAX(compacted, COALESCE((SELECT MAX(sequence) FROM pad_op WHERE pad_id=?), 0)) FROM pad WHERE id=?;
+
+-- name: v1-insert-op
+INSERT INTO pad_op (pad_id, sequence, content, created) VALUES (?, ?, ?, ?);
+
+-- name: v1-prune-ops
+DELETE FROM pad_op WHERE pad_id=? AND sequence<=?;
+
+-- name: v1-remove-ops
+DELETE FROM pad_op WHERE pad_id=?;
+
+-- name: v1-remove-expired-ops
+DELETE FROM pad_op WHERE pad_id IN (SELECT id FROM pad WHERE expires_at<>0 AND expires_at<=?);
+
+-- name: v1-insert-upload
+INSERT INTO pad_upload (id, pad_id, revision, chunks, size, expires_at, hashes) SELECT ?, ?, ?, ?, ?, ?, ? WHERE ?<=0 OR (SELECT COUNT(*) FROM pad_upload WHERE pad_id=? AND expires_at>?)<?;
+
+-- name: v1-upload-from-id
+SELECT id, pad_id, revision, chunks, size, expires_at, hashes FROM pad_upload WHERE id=?;
+
+-- name: v1-put-upload-chunk
+REPLACE INTO pad_upload_chunk (upload_id, idx, data, hash) SELECT ?, ?, ?, ? FROM pad_upload WHERE id=? AND (SELECT COALESCE(SUM(LENGTH(data)), 0) FROM pad_upload_chunk WHERE upload_id=? AND idx<>?)+?<=size;
+
+-- name: v1-upload-chunk-size
+SELECT LENGTH(data) FROM pad_upload_chunk WHERE upload_id=? AND idx=?;
+
+-- name: v1-upload-chunks-from-id
+SELECT idx, LENGTH(data), hash FROM pad_upload_chunk WHERE upload_id=? ORDER BY idx;
+
+-- name: v1-commit-upload-pad
+UPDATE pad SET content='', chunks=?, size=?, revision=revision+1 WHERE id=? AND revision=?;
+
+-- name: v1-commit-upload-chunks
+INSERT INTO pad_chunk (pad_id, idx, data) SELECT ?, idx, data FROM pad_upload_chunk WHERE upload_id=?;
+
+-- name: v1-remove-upload-chunks
+DELETE FROM pad_upload_chunk WHERE upload_id=?;
+
+-- name: v1-remove-upload
+DELETE FROM pad_upload WHERE id=?;
+
+-- name: v1-chunk-from-id
+SELECT data FROM pad_chunk WHERE pad_id=? AND idx=?;
+
+-- name: v1-remove-chunks
+DELETE FROM pad_chunk WHERE pad_id=?;
+
+-- name: v1-remove-pad-upload-chunks
+DELETE FROM pad_upload_chunk WHERE upload_id IN (SELECT id FROM pad_upload WHERE pad_id=?);
+
+-- name: v1-remove-pad-uploads
+DELETE FROM pad_upload WHERE pad_id=?;
+
+-- name: v1-remove-expired-chunks
+DELETE FROM pad_chunk WHERE pad_id IN (SELECT id FROM pad WHERE expires_at<>0 AND expires_at<=?);
+
+-- name: v1-remove-expired-upload-chunks
+DELETE FROM pad_upload_chunk WHERE upload_id IN (SELECT id FROM pad_upload WHERE expires_at<=?);
+
+-- name: v1-remove-expired-uploads
+DELETE FROM pad_upload WHERE expires_at<=? AND NOT EXISTS (SELECT 1 FROM pad WHERE pad.id=pad_upload.pad_id AND pad.revision=pad_upload.revision);
+
+-- name: v1-attachments-from-id
+SELECT id, size, created FROM pad_attachment WHERE pad_id=? ORDER BY created, id;
+
+-- name: v1-attachment-from-id
+SELECT id, size, created, data FROM pad_attachment WHERE pad_id=? AND id=?;
+
+-- name: v1-insert-attachment
+INSERT INTO pad_attachment (pad_id, id, size, created, data) SELECT ?, ?, ?, ?, ? WHERE (SELECT COUNT(*) FROM pad_attachment WHERE pad_id=?)<?;
+
+-- name: v1-remove-attachment
+DELETE FROM pad_attachment WHERE pad_id=? AND id=?;
+
+-- name: v1-remove-attachments
+DELETE FROM pad_attachment WHERE pad_id=?;
+
+-- name: v1-remove-expired-attachments
+DELETE FROM pad_attachment WHERE pad_id IN (SELECT id FROM pad WHERE expires_at<>0 AND expires_at<=?);
+
+-- name: v1-removed-attachment
+SELECT revision FROM pad_removed_attachment WHERE pad_id=? AND id=?;
+
+-- name: v1-insert-removed-attachment
+REPLACE INTO pad_removed_attachment (pad_id, id, revision) SELECT id, ?, revision FROM pad WHERE id=?;
+
+-- name: v1-remove-removed-attachments
+DELETE FROM pad_removed_attachment WHERE pad_id=?;
+
+-- name: v1-remove-stale-removed-attachments
+DELETE FROM pad_removed_attachment WHERE NOT EXISTS (SELECT 1 FROM pad WHERE pad.id=pad_removed_attachment.pad_id AND pad.revision=pad_removed_attachment.revision);
+
+-- name: v1-update-revision
+UPDATE pad_revision SET content=? WHERE pad_id=? AND revision=?;
+
+-- name: v1-count-revisions
+SELECT COUNT(*) FROM pad_revision WHERE pad_id=?;
+
+-- name: v1-update-attachment
+UPDATE pad_attachment SET size=?, data=? WHERE pad_id=? AND id=?;
+
+-- name: v1-count-attachments
+SELECT COUNT(*) FROM pad_attachment WHERE pad_id=?;
+
+-- name: v1-lockout-from-id
+SELECT failures, locked_until FROM pad_lockout WHERE pad_id=?;
+
+-- name: v1-add-failure
+INSERT INTO pad_lockout (pad_id, failures, locked_until) VALUES (?, 1, 0) ON CONFLICT (pad_id) DO UPDATE SET failures=failures+1;
+
+-- name: v1-lock-pad
+UPDATE pad_lockout SET locked_until=? WHERE pad_id=?;
+
+-- name: v1-remove-lockout
+DELETE FROM pad_lockout WHERE pad_id=?;
+
+-- name: v1-remove-expired-lockouts
+DELETE FROM pad_lockout WHERE pad_id IN (SELECT id FROM pad WHERE expires_at<>0 AND expires_at<=?);
+
+-- name: v1-pad-usage
+SELECT COUNT(*), COALESCE(SUM(size), 0) FROM pad;
+
+-- name: v1-attachment-usage
+SELECT COALESCE(SUM(size), 0) FROM pad_attachment;
+
+-- name: v1-upload-usage
+SELECT COALESCE(SUM(LENGTH(data)), 0) FROM pad_upload_chunk;
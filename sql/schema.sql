@@ -0,0 +1,91 @@
+-- The tables of a MySQL database, for use with queries.sql.
+-- Create a new database with: mysql cryptopad < sql/schema.sql
+
+CREATE TABLE IF NOT EXISTS pad (
+    id VARCHAR(16) NOT NULL PRIMARY KEY,
+    content BLOB NOT NULL,
+    proof VARCHAR(128) NOT NULL,
+    revision BIGINT NOT NULL DEFAULT 1,
+    expires_at BIGINT NOT NULL DEFAULT 0,
+    burn_after_reading BOOLEAN NOT NULL DEFAULT 0,
+    public_key VARCHAR(44) NOT NULL DEFAULT '',
+    read_token VARCHAR(64) NOT NULL DEFAULT '',
+    compacted BIGINT NOT NULL DEFAULT 0,
+    chunks INT NOT NULL DEFAULT 0,
+    size BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS pad_revision (
+    pad_id VARCHAR(16) NOT NULL,
+    revision BIGINT NOT NULL,
+    content BLOB NOT NULL,
+    created BIGINT NOT NULL,
+    PRIMARY KEY (pad_id, revision)
+);
+
+CREATE TABLE IF NOT EXISTS pad_burnt (
+    id VARCHAR(16) NOT NULL PRIMARY KEY,
+    expires_at BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS pad_editor (
+    pad_id VARCHAR(16) NOT NULL,
+    name VARCHAR(32) NOT NULL,
+    proof VARCHAR(128) NOT NULL,
+    PRIMARY KEY (pad_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS pad_op (
+    pad_id VARCHAR(16) NOT NULL,
+    sequence BIGINT NOT NULL,
+    content BLOB NOT NULL,
+    created BIGINT NOT NULL,
+    PRIMARY KEY (pad_id, sequence)
+);
+
+CREATE TABLE IF NOT EXISTS pad_upload (
+    id VARCHAR(43) NOT NULL PRIMARY KEY,
+    pad_id VARCHAR(16) NOT NULL,
+    revision BIGINT NOT NULL,
+    chunks INT NOT NULL,
+    size BIGINT NOT NULL,
+    expires_at BIGINT NOT NULL,
+    hashes MEDIUMTEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS pad_upload_chunk (
+    upload_id VARCHAR(43) NOT NULL,
+    idx INT NOT NULL,
+    data LONGBLOB NOT NULL,
+    hash VARCHAR(64) NOT NULL DEFAULT '',
+    PRIMARY KEY (upload_id, idx)
+);
+
+CREATE TABLE IF NOT EXISTS pad_chunk (
+    pad_id VARCHAR(16) NOT NULL,
+    idx INT NOT NULL,
+    data LONGBLOB NOT NULL,
+    PRIMARY KEY (pad_id, idx)
+);
+
+CREATE TABLE IF NOT EXISTS pad_attachment (
+    pad_id VARCHAR(16) NOT NULL,
+    id VARCHAR(22) NOT NULL,
+    size BIGINT NOT NULL,
+    created BIGINT NOT NULL,
+    data LONGBLOB NOT NULL,
+    PRIMARY KEY (pad_id, id)
+);
+
+CREATE TABLE IF NOT EXISTS pad_removed_attachment (
+    pad_id VARCHAR(16) NOT NULL,
+    id VARCHAR(22) NOT NULL,
+    revision BIGINT NOT NULL,
+    PRIMARY KEY (pad_id, id)
+);
+
+CREATE TABLE IF NOT EXISTS pad_lockout (
+    pad_id VARCHAR(16) NOT NULL PRIMARY KEY,
+    failures INT NOT NULL,
+    locked_until BIGINT NOT NULL
+);